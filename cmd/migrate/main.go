@@ -0,0 +1,122 @@
+// Command migrate applies, rolls back and reports the schema migrations
+// embedded in pkg/database.
+//
+// Usage:
+//
+//	migrate [--config file] [--dry-run] up
+//	migrate [--config file] [--dry-run] down [steps]
+//	migrate [--config file] [--dry-run] to <version>
+//	migrate [--config file] status
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/migrate"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file")
+	dryRun := fs.Bool("dry-run", false, "print the SQL that would run without applying it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return err
+	}
+	if err := logger.InitLogger(cfg.Log.Level); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	migrator, err := database.NewMigrator(db, migrate.Options{DryRun: *dryRun, Out: os.Stdout})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	command, rest := fs.Arg(0), fs.Args()
+	if len(rest) > 0 {
+		rest = rest[1:]
+	}
+
+	var done []migrate.Migration
+	switch command {
+	case "up":
+		done, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(rest) > 0 {
+			if steps, err = strconv.Atoi(rest[0]); err != nil {
+				return fmt.Errorf("invalid step count %q", rest[0])
+			}
+		}
+		done, err = migrator.Down(ctx, steps)
+	case "to":
+		if len(rest) == 0 {
+			return fmt.Errorf("usage: migrate to <version>")
+		}
+		version, parseErr := strconv.ParseInt(rest[0], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		done, err = migrator.To(ctx, version)
+	case "status":
+		return printStatus(ctx, migrator)
+	default:
+		return fmt.Errorf("usage: migrate [--config file] [--dry-run] up | down [steps] | to <version> | status")
+	}
+	if err != nil {
+		return err
+	}
+
+	verb := "applied"
+	if *dryRun {
+		verb = "would run"
+	}
+	fmt.Printf("%d migration(s) %s\n", len(done), verb)
+	return nil
+}
+
+func printStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state += " (modified since applied)"
+		}
+		fmt.Printf("%s  %s\n", status.Migration, state)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Command outbox inspects and re-queues dead-lettered emails.
+//
+// Usage:
+//
+//	outbox [--config file] dead [limit]
+//	outbox [--config file] retry <id>...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/outbox"
+)
+
+const usage = "usage: outbox [--config file] dead [limit] | retry <id>..."
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("outbox", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return err
+	}
+	if err := logger.InitLogger(cfg.Log.Level); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(&cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	command, rest := fs.Arg(0), fs.Args()
+	if len(rest) > 0 {
+		rest = rest[1:]
+	}
+
+	switch command {
+	case "dead":
+		limit := 50
+		if len(rest) == 1 {
+			if limit, err = strconv.Atoi(rest[0]); err != nil || limit < 1 {
+				return fmt.Errorf("invalid limit %q", rest[0])
+			}
+		} else if len(rest) > 1 {
+			return fmt.Errorf(usage)
+		}
+		messages, err := outbox.Dead(ctx, db, limit)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			fmt.Printf("%s  %s  %s  %d attempts  %s\n",
+				msg.ID, msg.UpdatedAt.Format("2006-01-02 15:04"), msg.Template, msg.Attempts, msg.LastError)
+		}
+		return nil
+	case "retry":
+		if len(rest) == 0 {
+			return fmt.Errorf(usage)
+		}
+		for _, id := range rest {
+			if err := outbox.Retry(ctx, db, id); err != nil {
+				return fmt.Errorf("%s: %v", id, err)
+			}
+			fmt.Printf("re-queued %s\n", id)
+		}
+		return nil
+	default:
+		return fmt.Errorf(usage)
+	}
+}
@@ -0,0 +1,145 @@
+// Command tenant manages organizations and their members across tenants.
+//
+// Usage:
+//
+//	tenant [--config file] create <slug> <company|institution> <name>
+//	tenant [--config file] list
+//	tenant [--config file] members <slug>
+//	tenant [--config file] add-member <slug> <user-id>
+//	tenant [--config file] remove-member <slug> <user-id>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/repository"
+	"github.com/hacKRD0/trikona_go/pkg/tenant"
+)
+
+const usage = "usage: tenant [--config file] create <slug> <type> <name> | list | members <slug> | add-member <slug> <user-id> | remove-member <slug> <user-id>"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("tenant", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return err
+	}
+	if err := logger.InitLogger(cfg.Log.Level); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	store := tenant.NewStore(db)
+
+	// Admin tooling acts across tenants
+	ctx := tenant.AllTenants(context.Background())
+	command, rest := fs.Arg(0), fs.Args()
+	if len(rest) > 0 {
+		rest = rest[1:]
+	}
+
+	switch command {
+	case "create":
+		if len(rest) < 3 {
+			return fmt.Errorf(usage)
+		}
+		org := &tenant.Organization{Slug: rest[0], Type: rest[1], Name: strings.Join(rest[2:], " ")}
+		if err := store.Create(ctx, org); err != nil {
+			return err
+		}
+		fmt.Printf("created organization %d (%s)\n", org.ID, org.Slug)
+		return nil
+	case "list":
+		return printOrganizations(ctx, store)
+	case "members":
+		if len(rest) != 1 {
+			return fmt.Errorf(usage)
+		}
+		org, err := store.GetBySlug(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		ids, err := store.Members(ctx, org.ID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	case "add-member", "remove-member":
+		if len(rest) != 2 {
+			return fmt.Errorf(usage)
+		}
+		org, err := store.GetBySlug(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		userID, err := strconv.ParseUint(rest[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid user ID %q", rest[1])
+		}
+		if command == "add-member" {
+			if err := store.AddMember(ctx, org.ID, uint(userID)); err != nil {
+				return err
+			}
+			fmt.Printf("added user %d to %s\n", userID, org.Slug)
+			return nil
+		}
+		if err := store.RemoveMember(ctx, org.ID, uint(userID)); err != nil {
+			return err
+		}
+		fmt.Printf("removed user %d from %s\n", userID, org.Slug)
+		return nil
+	default:
+		return fmt.Errorf(usage)
+	}
+}
+
+func printOrganizations(ctx context.Context, store *tenant.Store) error {
+	query, err := repository.ParseListQuery(url.Values{"limit": {strconv.Itoa(repository.MaxLimit)}}, tenant.ListSpec)
+	if err != nil {
+		return err
+	}
+	for {
+		page, err := store.List(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, org := range page.Data {
+			fmt.Printf("%d  %s  %s  %s\n", org.ID, org.Slug, org.Type, org.Name)
+		}
+		if !page.Pagination.HasMore {
+			return nil
+		}
+		query.Cursor = page.Pagination.NextCursor
+	}
+}
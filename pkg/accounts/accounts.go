@@ -0,0 +1,254 @@
+package accounts
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Account statuses
+const (
+	StatusActive      = "active"
+	StatusDeactivated = "deactivated"
+	// StatusDeleted accounts can be restored until their purge time
+	StatusDeleted = "deleted"
+)
+
+// Account holds the lifecycle columns of a user
+type Account struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Email         string         `json:"email"`
+	DeactivatedAt *time.Time     `json:"deactivated_at,omitempty"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty"`
+	// PurgeAfter is when a deleted account is hard deleted
+	PurgeAfter *time.Time `json:"purge_after,omitempty"`
+}
+
+// TableName overrides the table name used by gorm
+func (Account) TableName() string {
+	return "users"
+}
+
+// Status returns the lifecycle status of the account
+func (a *Account) Status() string {
+	switch {
+	case a.DeletedAt.Valid:
+		return StatusDeleted
+	case a.DeactivatedAt != nil:
+		return StatusDeactivated
+	default:
+		return StatusActive
+	}
+}
+
+// AnonymizedEmail is the placeholder that replaces the account's address in
+// records kept after it is purged
+func (a *Account) AnonymizedEmail() string {
+	return fmt.Sprintf("deleted-user-%d@invalid", a.ID)
+}
+
+// Cascade keeps the data of another table consistent with the account's
+// lifecycle. Each hook runs in the transaction that changes the account, so
+// a failing hook aborts the change; nil hooks are skipped.
+type Cascade struct {
+	Name string
+	// Delete runs when the account is soft deleted
+	Delete func(ctx context.Context, tx *gorm.DB, account *Account) error
+	// Restore runs when a deleted account is restored
+	Restore func(ctx context.Context, tx *gorm.DB, account *Account) error
+	// Purge runs before the account is hard deleted, and must delete or
+	// anonymize the rows that refer to it
+	Purge func(ctx context.Context, tx *gorm.DB, account *Account) error
+}
+
+// Service manages account deactivation, deletion and restoration
+type Service struct {
+	db     *gorm.DB
+	config *config.AccountConfig
+
+	mu       sync.RWMutex
+	cascades []Cascade
+}
+
+// NewService creates a new account service
+func NewService(db *gorm.DB, cfg *config.AccountConfig) *Service {
+	return &Service{db: db, config: cfg}
+}
+
+// RegisterCascade adds a cascade run on every lifecycle change
+func (s *Service) RegisterCascade(cascade Cascade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cascades = append(s.cascades, cascade)
+}
+
+// Get returns the account with the given ID, including deleted accounts
+func (s *Service) Get(ctx context.Context, id uint) (*Account, error) {
+	var account Account
+	err := database.Conn(ctx, s.db).Unscoped().First(&account, id).Error
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NewNotFoundError("account not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Deactivate hides the account until its owner reactivates it. Its data is
+// kept and nothing is purged.
+func (s *Service) Deactivate(ctx context.Context, id uint) error {
+	return s.change(ctx, id, func(ctx context.Context, tx *gorm.DB, account *Account) error {
+		if account.Status() != StatusActive {
+			return errors.NewConflictError("account is not active")
+		}
+		return tx.Model(account).Update("deactivated_at", time.Now()).Error
+	})
+}
+
+// Reactivate makes a deactivated account active again
+func (s *Service) Reactivate(ctx context.Context, id uint) error {
+	return s.change(ctx, id, func(ctx context.Context, tx *gorm.DB, account *Account) error {
+		if account.Status() != StatusDeactivated {
+			return errors.NewConflictError("account is not deactivated")
+		}
+		return tx.Model(account).Update("deactivated_at", nil).Error
+	})
+}
+
+// Delete soft deletes the account. It can be restored within the restore
+// window, after which the purger hard deletes it.
+func (s *Service) Delete(ctx context.Context, id uint) error {
+	return s.change(ctx, id, func(ctx context.Context, tx *gorm.DB, account *Account) error {
+		if account.Status() == StatusDeleted {
+			return errors.NewNotFoundError("account not found")
+		}
+
+		now := time.Now()
+		purgeAfter := now.Add(s.config.RestoreWindow)
+		err := tx.Model(account).Updates(map[string]interface{}{
+			"deleted_at":  now,
+			"purge_after": purgeAfter,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		account.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		account.PurgeAfter = &purgeAfter
+		return s.runCascades(ctx, tx, account, stageDelete)
+	})
+}
+
+// Restore undoes the deletion of an account within its restore window
+func (s *Service) Restore(ctx context.Context, id uint) error {
+	return s.change(ctx, id, func(ctx context.Context, tx *gorm.DB, account *Account) error {
+		if account.Status() != StatusDeleted || account.PurgeAfter == nil || !time.Now().Before(*account.PurgeAfter) {
+			return errors.NewNotFoundError("no restorable account")
+		}
+
+		err := tx.Model(account).Updates(map[string]interface{}{
+			"deleted_at":  nil,
+			"purge_after": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		account.DeletedAt = gorm.DeletedAt{}
+		account.PurgeAfter = nil
+		return s.runCascades(ctx, tx, account, stageRestore)
+	})
+}
+
+// CheckLogin decides whether the account may log in. Call it after the
+// credentials are verified, with the user looked up including soft deleted
+// rows. A deactivated account is reactivated when ACCOUNT_REACTIVATE_ON_LOGIN
+// is set and refused otherwise; a deleted account is refused with a hint
+// that it can still be restored.
+func (s *Service) CheckLogin(ctx context.Context, id uint) error {
+	account, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch account.Status() {
+	case StatusDeactivated:
+		if !s.config.ReactivateOnLogin {
+			return errors.NewAuthenticationError("account is deactivated").
+				WithMessageID("auth.account.deactivated", nil)
+		}
+		if err := s.Reactivate(ctx, id); err != nil {
+			return err
+		}
+		logger.Info("Account reactivated on login", zap.Uint("user_id", id))
+	case StatusDeleted:
+		return errors.NewAuthenticationError("account is scheduled for deletion and can be restored").
+			WithMessageID("auth.account.deleted", nil)
+	}
+	return nil
+}
+
+// change locks the account and applies fn in a unit of work
+func (s *Service) change(ctx context.Context, id uint, fn func(ctx context.Context, tx *gorm.DB, account *Account) error) error {
+	return database.WithTx(ctx, s.db, func(ctx context.Context, tx *gorm.DB) error {
+		var account Account
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, id).Error
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NewNotFoundError("account not found")
+		}
+		if err != nil {
+			return err
+		}
+		return fn(ctx, tx.Unscoped(), &account)
+	})
+}
+
+// stage is a lifecycle change that cascades run on
+type stage int
+
+const (
+	stageDelete stage = iota
+	stageRestore
+	stagePurge
+)
+
+// hook returns the cascade's hook for the stage
+func (c Cascade) hook(st stage) func(ctx context.Context, tx *gorm.DB, account *Account) error {
+	switch st {
+	case stageDelete:
+		return c.Delete
+	case stageRestore:
+		return c.Restore
+	default:
+		return c.Purge
+	}
+}
+
+// runCascades runs the hooks of every cascade for the stage
+func (s *Service) runCascades(ctx context.Context, tx *gorm.DB, account *Account, st stage) error {
+	s.mu.RLock()
+	cascades := s.cascades
+	s.mu.RUnlock()
+
+	for _, cascade := range cascades {
+		fn := cascade.hook(st)
+		if fn == nil {
+			continue
+		}
+		if err := fn(ctx, tx, account); err != nil {
+			return fmt.Errorf("failed to run %s cascade: %v", cascade.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package accounts
+
+import (
+	"context"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+)
+
+// Locale returns the stored locale preference of the account, or "" when
+// the user has not chosen one
+func (s *Service) Locale(ctx context.Context, id uint) (string, error) {
+	var locales []*string
+	err := database.Conn(ctx, s.db).Model(&Account{}).
+		Where("id = ?", id).
+		Limit(1).
+		Pluck("locale", &locales).Error
+	if err != nil {
+		return "", err
+	}
+	if len(locales) == 0 {
+		return "", errors.NewNotFoundError("account not found")
+	}
+	if locales[0] == nil {
+		return "", nil
+	}
+	return *locales[0], nil
+}
+
+// SetLocale stores the locale preference of the account; "" clears it.
+// Callers validate the locale against the catalog with
+// validation.ValidateLocale.
+func (s *Service) SetLocale(ctx context.Context, id uint, locale string) error {
+	var value interface{}
+	if locale = i18n.Canonicalize(locale); locale != "" {
+		value = locale
+	}
+	result := database.Conn(ctx, s.db).Model(&Account{ID: id}).Update("locale", value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("account not found")
+	}
+	return nil
+}
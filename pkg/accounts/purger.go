@@ -0,0 +1,143 @@
+package accounts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/audit"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Purger hard deletes accounts whose restore window has passed, after the
+// cascades have deleted or anonymized the rows referring to them
+type Purger struct {
+	service *Service
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPurger creates a purger for the accounts of service
+func NewPurger(service *Service) *Purger {
+	return &Purger{service: service}
+}
+
+// Start runs a purge every ACCOUNT_PURGE_INTERVAL until Stop is called or
+// ctx is cancelled
+func (p *Purger) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.service.config.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := p.PurgeDue(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Failed to purge deleted accounts", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logger.Info("Account purger started", zap.Duration("interval", p.service.config.PurgeInterval))
+}
+
+// Stop stops the purger and waits for a running purge to finish, or for
+// ctx to expire
+func (p *Purger) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("Account purger stopped")
+		return nil
+	case <-ctx.Done():
+		logger.Warn("Account purger did not stop before the deadline")
+		return ctx.Err()
+	}
+}
+
+// PurgeDue purges up to ACCOUNT_PURGE_BATCH_SIZE accounts past their
+// restore window and returns how many were purged. Each account is purged
+// in its own transaction, so one failing cascade does not hold back the rest.
+func (p *Purger) PurgeDue(ctx context.Context) (int, error) {
+	var ids []uint
+	err := database.Conn(ctx, p.service.db).Unscoped().Model(&Account{}).
+		Where("deleted_at IS NOT NULL AND purge_after <= ?", time.Now()).
+		Order("purge_after").
+		Limit(p.service.config.PurgeBatchSize).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := p.purge(ctx, id)
+		if err != nil {
+			logger.Error("Failed to purge account", err, zap.Uint("user_id", id))
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		logger.Info("Purged deleted accounts", zap.Int("count", purged))
+	}
+	return purged, nil
+}
+
+// purge runs the purge cascades and hard deletes one account. Accounts
+// restored or locked by another purger in the meantime are skipped. The
+// audit trail records the purge without the purged values.
+func (p *Purger) purge(ctx context.Context, id uint) (bool, error) {
+	ctx = audit.WithoutValues(ctx)
+	purged := false
+	err := database.WithTx(ctx, p.service.db, func(ctx context.Context, tx *gorm.DB) error {
+		var account Account
+		result := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("deleted_at IS NOT NULL AND purge_after <= ?", time.Now()).
+			Limit(1).
+			Find(&account, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := p.service.runCascades(ctx, tx, &account, stagePurge); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&account).Error; err != nil {
+			return err
+		}
+		purged = true
+		return nil
+	})
+	return purged, err
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/auth"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit trail
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// chainLockClass is the first key of the transaction-level advisory locks
+// serializing appends to a table's chain; the second is the hash of the
+// table name, so tables do not wait on each other
+const chainLockClass = 724_610_531
+
+// redactedValue replaces the values of redacted columns
+const redactedValue = "***"
+
+// ErrChainBroken is returned by Verify when an entry was modified, removed
+// or inserted out of band
+var ErrChainBroken = errors.New("audit chain is broken")
+
+// Change is the value of a column before and after a change; Old is unset
+// for creates and New for deletes
+type Change struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// Entry is a change to an audited record. Entries are append-only and
+// hash-chained per table: each hash covers the entry and the hash of the
+// previous entry of the same table, so editing or deleting an entry breaks
+// the chain after it.
+type Entry struct {
+	ID         int64           `gorm:"primaryKey" json:"id"`
+	OccurredAt time.Time       `gorm:"not null" json:"occurred_at"`
+	Table      string          `gorm:"column:table_name;size:100;not null" json:"table"`
+	RecordID   string          `gorm:"size:255;not null" json:"record_id"`
+	Action     string          `gorm:"size:10;not null" json:"action"`
+	ActorID    string          `gorm:"size:100" json:"actor_id,omitempty"`
+	ActorRole  string          `gorm:"size:50" json:"actor_role,omitempty"`
+	RequestID  string          `gorm:"size:100" json:"request_id,omitempty"`
+	Changes    json.RawMessage `gorm:"type:jsonb;not null" json:"changes"`
+	PrevHash   string          `gorm:"size:64;not null" json:"prev_hash"`
+	Hash       string          `gorm:"size:64;not null" json:"hash"`
+}
+
+// TableName overrides the table name used by gorm
+func (Entry) TableName() string {
+	return "audit_log"
+}
+
+type withoutValuesKey struct{}
+
+// WithoutValues returns a context whose changes are audited without their
+// values, e.g. when purging personal data that must not outlive the purge
+func WithoutValues(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutValuesKey{}, true)
+}
+
+// newEntry creates an entry attributed to the principal and request of ctx.
+// The actor is recorded by ID only, so the entry holds no personal data.
+func newEntry(ctx context.Context, table, recordID, action string, changes map[string]Change) (*Entry, error) {
+	data, err := canonicalJSON(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit changes: %v", err)
+	}
+
+	entry := &Entry{
+		// Postgres keeps microseconds; truncating keeps the hash stable
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		Table:      table,
+		RecordID:   recordID,
+		Action:     action,
+		RequestID:  logger.RequestIDFromContext(ctx),
+		Changes:    data,
+	}
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		entry.ActorID = claims.UserID
+		entry.ActorRole = claims.Role
+	}
+	return entry, nil
+}
+
+// computeHash returns the hash of the entry chained to PrevHash
+func (e *Entry) computeHash() (string, error) {
+	changes, err := canonicalJSON(e.Changes)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		e.PrevHash,
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Table,
+		e.RecordID,
+		e.Action,
+		e.ActorID,
+		e.ActorRole,
+		e.RequestID,
+		string(changes),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalJSON encodes v so that the same content always gives the same
+// bytes, whatever key order or spacing jsonb hands back
+func canonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// appendEntries chains entries to the last stored entry of their table and
+// inserts them in the transaction of db, so they commit or roll back with the
+// change. The chain lock of each table is held until that transaction ends.
+func appendEntries(db *gorm.DB, entries []*Entry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var tables []string
+		prev := map[string]string{}
+		for _, entry := range entries {
+			if _, ok := prev[entry.Table]; !ok {
+				prev[entry.Table] = ""
+				tables = append(tables, entry.Table)
+			}
+		}
+		// A fixed lock order keeps transactions writing several tables
+		// from deadlocking
+		sort.Strings(tables)
+
+		for _, table := range tables {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", chainLockClass, table).Error; err != nil {
+				return err
+			}
+			var last []string
+			err := tx.Model(&Entry{}).Where("table_name = ?", table).Order("id DESC").Limit(1).Pluck("hash", &last).Error
+			if err != nil {
+				return err
+			}
+			if len(last) > 0 {
+				prev[table] = last[0]
+			}
+		}
+
+		for _, entry := range entries {
+			entry.PrevHash = prev[entry.Table]
+			hash, err := entry.computeHash()
+			if err != nil {
+				return fmt.Errorf("failed to hash audit entry: %v", err)
+			}
+			entry.Hash = hash
+			prev[entry.Table] = hash
+		}
+		return tx.Create(&entries).Error
+	})
+}
@@ -0,0 +1,358 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// snapshotKey is the statement setting holding the rows read before an
+// update or delete
+const snapshotKey = "audit:snapshot"
+
+// commitCallback is gorm's transaction callback; audit callbacks run before
+// it so that entries are written in the transaction of the change
+const commitCallback = "gorm:commit_or_rollback_transaction"
+
+// Config configures the audit trail
+type Config struct {
+	// Tables are the audited tables
+	Tables []string
+	// Redact lists columns whose values are not recorded, only that they
+	// changed; password and the personalData columns are always redacted
+	Redact []string
+}
+
+// personalData are the columns holding personal data. The trail is
+// append-only, so values recorded there would outlive an account purge.
+var personalData = []string{"email", "first_name", "last_name", "linkedin_id"}
+
+// Auditor is a gorm plugin recording creates, updates and deletes of the
+// configured tables in the audit trail, in the same transaction as the
+// change. Changes made with raw SQL (Exec) are not audited.
+//
+// Updates and deletes read the affected rows before and after the change to
+// compute the diff, so they cost two extra queries.
+type Auditor struct {
+	tables map[string]bool
+	redact map[string]bool
+}
+
+// New creates an audit plugin; install it with db.Use
+func New(cfg Config) *Auditor {
+	a := &Auditor{
+		tables: make(map[string]bool),
+		redact: map[string]bool{"password": true},
+	}
+	for _, table := range cfg.Tables {
+		a.tables[table] = true
+	}
+	for _, column := range personalData {
+		a.redact[column] = true
+	}
+	for _, column := range cfg.Redact {
+		a.redact[column] = true
+	}
+	return a
+}
+
+// Name implements gorm.Plugin
+func (a *Auditor) Name() string {
+	return "audit"
+}
+
+// Initialize implements gorm.Plugin by registering the audit callbacks. The
+// trail needs Postgres: it serializes appends with an advisory lock and
+// stores changes as jsonb.
+func (a *Auditor) Initialize(db *gorm.DB) error {
+	if name := db.Dialector.Name(); name != "postgres" {
+		return fmt.Errorf("audit trail requires postgres, not %s", name)
+	}
+	if err := db.Callback().Create().After("gorm:create").Before(commitCallback).Register("audit:create", a.afterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("audit:snapshot", a.snapshot); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Before(commitCallback).Register("audit:update", a.afterUpdate); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("audit:snapshot", a.snapshot); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Before(commitCallback).Register("audit:delete", a.afterDelete)
+}
+
+func (a *Auditor) audited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && a.tables[db.Statement.Table]
+}
+
+// afterCreate records the values of the created records
+func (a *Auditor) afterCreate(db *gorm.DB) {
+	if !a.audited(db) || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	var entries []*Entry
+	for _, row := range a.structRows(db) {
+		changes := make(map[string]Change, len(row))
+		for column, value := range row {
+			changes[column] = Change{New: value}
+		}
+		if err := a.add(db, &entries, row, ActionCreate, changes); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+	a.write(db, entries)
+}
+
+// snapshot reads the rows an update or delete is about to change
+func (a *Auditor) snapshot(db *gorm.DB) {
+	if !a.audited(db) {
+		return
+	}
+	rows, err := a.load(db, a.conditions(db))
+	if err != nil {
+		db.AddError(fmt.Errorf("failed to read audited rows: %v", err))
+		return
+	}
+	db.InstanceSet(snapshotKey, rows)
+}
+
+// afterUpdate records the columns that changed in each updated row
+func (a *Auditor) afterUpdate(db *gorm.DB) {
+	a.afterChange(db, ActionUpdate)
+}
+
+// afterDelete records the last values of hard deleted rows, and the
+// changed columns of soft deleted ones
+func (a *Auditor) afterDelete(db *gorm.DB) {
+	a.afterChange(db, ActionDelete)
+}
+
+// afterChange reads the snapshot rows again and records how each changed.
+// Rows the statement did not touch, such as soft deleted rows it skipped,
+// are left out.
+func (a *Auditor) afterChange(db *gorm.DB, action string) {
+	before, ok := a.snapshotRows(db)
+	if !ok || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	after, err := a.load(db, a.primaryKeyIn(db, before))
+	if err != nil {
+		db.AddError(fmt.Errorf("failed to read audited rows: %v", err))
+		return
+	}
+	afterByID := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		afterByID[a.recordID(db, row)] = row
+	}
+
+	var entries []*Entry
+	for _, old := range before {
+		var changes map[string]Change
+		if updated, ok := afterByID[a.recordID(db, old)]; ok {
+			changes = diff(old, updated)
+		} else {
+			changes = make(map[string]Change, len(old))
+			for column, value := range old {
+				changes[column] = Change{Old: value}
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		if err := a.add(db, &entries, old, action, changes); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+	a.write(db, entries)
+}
+
+func (a *Auditor) snapshotRows(db *gorm.DB) ([]map[string]interface{}, bool) {
+	if !a.audited(db) {
+		return nil, false
+	}
+	value, ok := db.InstanceGet(snapshotKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := value.([]map[string]interface{})
+	return rows, ok
+}
+
+// add appends the entry of a row, with redacted values removed
+func (a *Auditor) add(db *gorm.DB, entries *[]*Entry, row map[string]interface{}, action string, changes map[string]Change) error {
+	_, withoutValues := db.Statement.Context.Value(withoutValuesKey{}).(bool)
+	for column, change := range changes {
+		if withoutValues || a.redact[column] {
+			if change.Old != nil {
+				change.Old = redactedValue
+			}
+			if change.New != nil {
+				change.New = redactedValue
+			}
+			changes[column] = change
+		}
+	}
+
+	entry, err := newEntry(db.Statement.Context, db.Statement.Table, a.recordID(db, row), action, changes)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, entry)
+	return nil
+}
+
+// write appends the entries in the transaction of the change, failing the
+// change when they cannot be stored
+func (a *Auditor) write(db *gorm.DB, entries []*Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	session := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	if err := appendEntries(session, entries); err != nil {
+		db.AddError(fmt.Errorf("failed to write audit trail: %v", err))
+	}
+}
+
+// load reads the rows of the statement's table matching conditions from
+// the primary, including soft deleted rows
+func (a *Auditor) load(db *gorm.DB, conditions []clause.Expression) ([]map[string]interface{}, error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	var rows []map[string]interface{}
+	// The context is set in the same session: chaining WithContext would
+	// carry over the SQL of the statement being audited
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true, Context: database.UsePrimary(db.Statement.Context)}).
+		Unscoped().
+		Model(reflect.New(db.Statement.Schema.ModelType).Interface()).
+		Clauses(clause.Where{Exprs: conditions}).
+		Find(&rows).Error
+	return rows, err
+}
+
+// conditions returns the WHERE conditions of the statement, with those on
+// the primary keys of its model, as gorm adds them when running it
+func (a *Auditor) conditions(db *gorm.DB) []clause.Expression {
+	stmt := db.Statement
+	var conditions []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conditions = append(conditions, where.Exprs...)
+		}
+	}
+
+	if stmt.ReflectValue.Kind() == reflect.Struct && stmt.ReflectValue.Type() == stmt.Schema.ModelType {
+		for _, field := range stmt.Schema.PrimaryFields {
+			if value, zero := field.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+				conditions = append(conditions, clause.Eq{Column: column(field), Value: value})
+			}
+		}
+	}
+	if stmt.ReflectValue.Kind() == reflect.Slice && len(stmt.Schema.PrimaryFields) == 1 {
+		field := stmt.Schema.PrimaryFields[0]
+		var ids []interface{}
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			if value, zero := field.ValueOf(stmt.Context, reflect.Indirect(stmt.ReflectValue.Index(i))); !zero {
+				ids = append(ids, value)
+			}
+		}
+		if len(ids) > 0 {
+			conditions = append(conditions, clause.IN{Column: column(field), Values: ids})
+		}
+	}
+	return conditions
+}
+
+// primaryKeyIn returns the condition selecting the rows again after the change
+func (a *Auditor) primaryKeyIn(db *gorm.DB, rows []map[string]interface{}) []clause.Expression {
+	var conditions []clause.Expression
+	for _, field := range db.Statement.Schema.PrimaryFields {
+		values := make([]interface{}, len(rows))
+		for i, row := range rows {
+			values[i] = row[field.DBName]
+		}
+		conditions = append(conditions, clause.IN{Column: column(field), Values: values})
+	}
+	return conditions
+}
+
+// structRows returns the column values of the records of a create
+func (a *Auditor) structRows(db *gorm.DB) []map[string]interface{} {
+	stmt := db.Statement
+	value := reflect.Indirect(stmt.ReflectValue)
+
+	var records []reflect.Value
+	switch value.Kind() {
+	case reflect.Struct:
+		records = append(records, value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			records = append(records, reflect.Indirect(value.Index(i)))
+		}
+	}
+
+	rows := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		if record.Kind() != reflect.Struct {
+			continue
+		}
+		row := make(map[string]interface{}, len(stmt.Schema.DBNames))
+		for _, name := range stmt.Schema.DBNames {
+			row[name], _ = stmt.Schema.FieldsByDBName[name].ValueOf(stmt.Context, record)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// recordID returns the primary key of a row, joining composite keys with commas
+func (a *Auditor) recordID(db *gorm.DB, row map[string]interface{}) string {
+	ids := make([]string, len(db.Statement.Schema.PrimaryFields))
+	for i, field := range db.Statement.Schema.PrimaryFields {
+		ids[i] = fmt.Sprint(row[field.DBName])
+	}
+	return strings.Join(ids, ",")
+}
+
+func column(field *schema.Field) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+}
+
+// diff returns the columns whose values differ between two rows. A change
+// of updated_at alone is no change.
+func diff(old, updated map[string]interface{}) map[string]Change {
+	changes := make(map[string]Change)
+	for column, value := range updated {
+		if !equal(old[column], value) {
+			changes[column] = Change{Old: old[column], New: value}
+		}
+	}
+	if _, touched := changes["updated_at"]; touched && len(changes) == 1 {
+		return nil
+	}
+	return changes
+}
+
+func equal(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize is the number of entries Verify reads at a time
+const verifyBatchSize = 500
+
+// ListSpec whitelists the audit trail fields admin endpoints may filter
+// and sort by
+var ListSpec = repository.ListSpec{
+	Fields: map[string]repository.Field{
+		"id":          {Column: "id", Sortable: true},
+		"table":       {Column: "table_name", Ops: []repository.Op{repository.OpEq, repository.OpIn}},
+		"record_id":   {Column: "record_id", Ops: []repository.Op{repository.OpEq}},
+		"action":      {Column: "action", Ops: []repository.Op{repository.OpEq, repository.OpIn}},
+		"actor_id":    {Column: "actor_id", Ops: []repository.Op{repository.OpEq}},
+		"request_id":  {Column: "request_id", Ops: []repository.Op{repository.OpEq}},
+		"occurred_at": {Column: "occurred_at", Ops: []repository.Op{repository.OpGte, repository.OpLt}, Sortable: true, Parse: repository.ParseTime},
+	},
+	DefaultSort: "-id",
+}
+
+// NewRepository returns a read repository over the audit trail, e.g. for
+// GET /admin/audit?table=users&record_id=42. Entries must not be changed
+// through it; the table rejects updates and deletes.
+func NewRepository(db *gorm.DB) *repository.Repository[Entry] {
+	return repository.New[Entry](db, ListSpec)
+}
+
+// History returns the entries of a record, oldest first
+func History(ctx context.Context, db *gorm.DB, table, recordID string) ([]Entry, error) {
+	var entries []Entry
+	err := database.Conn(ctx, db).
+		Where("table_name = ? AND record_id = ?", table, recordID).
+		Order("id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// Verify walks the chain of every table and returns an ErrChainBroken
+// error naming the first entry whose hash does not match its content or
+// the previous entry of its table
+func Verify(ctx context.Context, db *gorm.DB) error {
+	prev := map[string]string{}
+	var lastID int64
+	for {
+		var batch []Entry
+		err := database.Conn(ctx, db).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(verifyBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return fmt.Errorf("failed to read audit trail: %v", err)
+		}
+
+		for i := range batch {
+			entry := &batch[i]
+			if entry.PrevHash != prev[entry.Table] {
+				return fmt.Errorf("%w: entry %d does not follow the previous entry", ErrChainBroken, entry.ID)
+			}
+			hash, err := entry.computeHash()
+			if err != nil {
+				return fmt.Errorf("failed to hash audit entry %d: %v", entry.ID, err)
+			}
+			if hash != entry.Hash {
+				return fmt.Errorf("%w: entry %d has been modified", ErrChainBroken, entry.ID)
+			}
+			prev[entry.Table] = entry.Hash
+			lastID = entry.ID
+		}
+
+		if len(batch) < verifyBatchSize {
+			return nil
+		}
+	}
+}
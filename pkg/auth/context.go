@@ -0,0 +1,20 @@
+package auth
+
+import "context"
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a context carrying the claims of the
+// authenticated user. Authentication middleware stores them on the request
+// context so that code below the HTTP layer, such as the audit trail, knows
+// who is acting.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims carried by ctx, or nil for
+// unauthenticated requests and background work
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
@@ -12,6 +12,7 @@ import (
 // JWTService defines the interface for JWT operations
 type JWTService interface {
 	GenerateToken(userID string, email string, role string) (string, error)
+	GenerateTenantToken(userID string, email string, role string, tenantID string) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	ExtractClaims(token *jwt.Token) (*Claims, error)
 }
@@ -21,6 +22,8 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// TenantID is the organization the user acts for, if any
+	TenantID string `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -37,16 +40,24 @@ func NewJWTService(secret string) JWTService {
 
 // GenerateToken creates a new JWT token
 func (s *jwtService) GenerateToken(userID string, email string, role string) (string, error) {
+	return s.GenerateTenantToken(userID, email, role, "")
+}
+
+// GenerateTenantToken creates a new JWT token for a member of an
+// organization; tenantID is empty for users outside any organization
+func (s *jwtService) GenerateTenantToken(userID string, email string, role string, tenantID string) (string, error) {
 	logger.Info("Generating JWT token",
 		zap.String("user_id", userID),
 		zap.String("email", email),
 		zap.String("role", role),
+		zap.String("tenant_id", tenantID),
 	)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		TenantID: tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
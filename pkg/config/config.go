@@ -1,17 +1,260 @@
 package config
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/hacKRD0/trikona_go/pkg/environment"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
 	"github.com/joho/godotenv"
 )
 
+// Config is the typed application configuration
+//
+// Fields are populated from the environment variable named by the env tag,
+// falling back to the default tag. Fields tagged required must be set, and
+// the comma-separated rules of the validate tag (url, email, port, oneof=a b,
+// min=n) are checked by Validate. Fields tagged secret hold credentials, and
+// fields tagged reload can be applied by a Watcher without a restart.
+type Config struct {
+	// Env accepts the aliases understood by environment.Parse, such as
+	// "prod", and holds the canonical name once loaded
+	Env         environment.Env `env:"ENV" default:"development" validate:"oneof=development test staging production"`
+	FrontendURL string          `env:"FRONTEND_URL" required:"true" validate:"url"`
+
+	Server   ServerConfig
+	Database DatabaseConfig
+	JWT      JWTConfig
+	Mail     MailConfig
+	LinkedIn LinkedInConfig `file:"linkedin"`
+	Log      LogConfig
+	Cors     CorsConfig
+	Account  AccountConfig
+	Tenant   TenantConfig
+
+	// sources records which source supplied each setting, keyed by env key
+	sources map[string]string
+	// files lists the config and .env file paths that were read or could
+	// be, for change detection
+	files []string
+}
+
+// ServerConfig holds the HTTP server configuration
+type ServerConfig struct {
+	Port int `env:"PORT" default:"8080" validate:"port"`
+}
+
+// DatabaseConfig holds the Postgres connection configuration
+type DatabaseConfig struct {
+	// URL is a postgres:// connection URL; when set it replaces the
+	// individual connection settings below
+	URL      string `env:"DATABASE_URL" secret:"true"`
+	Host     string `env:"DB_HOST"`
+	Port     int    `env:"DB_PORT" default:"5432" validate:"port"`
+	User     string `env:"DB_USER"`
+	Password string `env:"DB_PASSWORD" secret:"true"`
+	Name     string `env:"DB_NAME"`
+	// SSLMode defaults to "require" in production and "disable" otherwise.
+	// verify-ca checks the server certificate against SSLRootCert (or the
+	// system roots); verify-full also checks the host name.
+	SSLMode string `env:"DB_SSLMODE" validate:"oneof=disable require verify-ca verify-full"`
+	// SSLRootCert is the path of the CA certificate for verify-ca and verify-full
+	SSLRootCert string `env:"DB_SSLROOTCERT"`
+	// SSLCert and SSLKey are the paths of the client certificate and key
+	// for certificate authentication
+	SSLCert     string `env:"DB_SSLCERT"`
+	SSLKey      string `env:"DB_SSLKEY"`
+	SSLPassword string `env:"DB_SSLPASSWORD" secret:"true"`
+
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"25" validate:"min=1"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"10" validate:"min=0"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+	// ConnectTimeout bounds the startup retries while Postgres is unavailable
+	ConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" default:"60s"`
+
+	// LogLevel is the level of the query log: silent, error (failed
+	// queries), warn (also slow queries) or info (also sampled queries)
+	LogLevel string `env:"DB_LOG_LEVEL" default:"warn" validate:"oneof=silent error warn info"`
+	// SlowQueryThreshold is the duration above which a query is logged as
+	// slow; 0 disables slow query logging
+	SlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
+	// LogSampleRate is the fraction of ordinary queries logged at the info
+	// level, between 0 and 1
+	LogSampleRate float64 `env:"DB_LOG_SAMPLE_RATE" default:"1"`
+	// LogParams includes query parameters in the query log; they may hold
+	// personal data, so they are redacted by default
+	LogParams bool `env:"DB_LOG_PARAMS" default:"false"`
+
+	// ReplicaURLs are postgres:// URLs of read replicas; reads are spread
+	// across the healthy ones
+	ReplicaURLs []string `env:"DB_REPLICA_URLS" secret:"true"`
+	// ReplicaHealthInterval is how often replicas are health checked
+	ReplicaHealthInterval time.Duration `env:"DB_REPLICA_HEALTH_INTERVAL" default:"10s"`
+	// ReplicaMaxLag takes a replica out of rotation while it is further
+	// behind the primary; 0 disables the lag check
+	ReplicaMaxLag time.Duration `env:"DB_REPLICA_MAX_LAG" default:"30s"`
+}
+
+// JWTConfig holds the token signing configuration
+type JWTConfig struct {
+	Secret string `env:"JWT_SECRET" required:"true" secret:"true" validate:"min=32"`
+}
+
+// MailConfig holds the mailer configuration
+type MailConfig struct {
+	Transport        string `env:"MAILER_TRANSPORT" default:"mailjet" validate:"oneof=mailjet smtp file memory"`
+	FromEmail        string `env:"MAILJET_FROM_EMAIL" required:"true" validate:"email"`
+	FromName         string `env:"MAILJET_FROM_NAME"`
+	MailjetAPIKey    string `env:"MAILJET_API_KEY" secret:"true"`
+	MailjetSecretKey string `env:"MAILJET_SECRET_KEY" secret:"true"`
+	SMTPHost         string `env:"SMTP_HOST"`
+	SMTPPort         int    `env:"SMTP_PORT" default:"587" validate:"port"`
+	SMTPUsername     string `env:"SMTP_USERNAME"`
+	SMTPPassword     string `env:"SMTP_PASSWORD" secret:"true"`
+	FileDir          string `env:"MAILER_FILE_DIR"`
+	// PreviewAddr is the listen address of the development mail preview
+	// server used with the memory transport
+	PreviewAddr string `env:"MAILER_PREVIEW_ADDR" default:":8025"`
+	// SendRate and SendBurst cap the overall delivery rate (messages per
+	// second) to stay within the provider's limits; 0 disables the cap
+	SendRate        float64 `env:"MAILER_SEND_RATE" default:"0" reload:"true"`
+	SendBurst       int     `env:"MAILER_SEND_BURST" default:"10" reload:"true"`
+	WebhookUsername string  `env:"MAILJET_WEBHOOK_USERNAME"`
+	WebhookPassword string  `env:"MAILJET_WEBHOOK_PASSWORD" secret:"true"`
+	// UnsubscribeSecret signs unsubscribe links
+	UnsubscribeSecret string `env:"UNSUBSCRIBE_SECRET" required:"true" secret:"true" validate:"min=16"`
+}
+
+// LinkedInConfig holds LinkedIn OAuth configuration
+type LinkedInConfig struct {
+	ClientID     string `env:"LINKEDIN_CLIENT_ID"`
+	ClientSecret string `env:"LINKEDIN_CLIENT_SECRET" secret:"true"`
+	RedirectURI  string `env:"LINKEDIN_REDIRECT_URI" validate:"url"`
+	TokenURL     string `env:"LINKEDIN_TOKEN_URL" default:"https://www.linkedin.com/oauth/v2/accessToken" validate:"url"`
+	ProfileURL   string `env:"LINKEDIN_PROFILE_URL" default:"https://api.linkedin.com/v2/me?projection=(id,firstName,lastName,profilePicture(displayImage~:playableStreams))" validate:"url"`
+}
+
+// LogConfig holds the logger configuration
+type LogConfig struct {
+	// Level defaults to logger.DefaultLevel for the environment
+	Level string `env:"LOG_LEVEL" validate:"oneof=debug info warn error" reload:"true"`
+}
+
+// CorsConfig holds the CORS configuration
+type CorsConfig struct {
+	AllowOrigins []string `env:"CORS_ALLOW_ORIGINS" default:"*" reload:"true"`
+}
+
+// AccountConfig holds the account lifecycle configuration
+type AccountConfig struct {
+	// RestoreWindow is how long a deleted account can be restored before
+	// it is purged
+	RestoreWindow time.Duration `env:"ACCOUNT_RESTORE_WINDOW" default:"720h"`
+	// ReactivateOnLogin reactivates a deactivated account when its owner
+	// logs in; otherwise the login is refused
+	ReactivateOnLogin bool `env:"ACCOUNT_REACTIVATE_ON_LOGIN" default:"true"`
+	// PurgeInterval is how often deleted accounts past their restore
+	// window are purged
+	PurgeInterval time.Duration `env:"ACCOUNT_PURGE_INTERVAL" default:"1h"`
+	// PurgeBatchSize is the maximum number of accounts purged per run
+	PurgeBatchSize int `env:"ACCOUNT_PURGE_BATCH_SIZE" default:"100" validate:"min=1"`
+}
+
+// TenantConfig holds the organization tenancy configuration
+type TenantConfig struct {
+	// BaseDomain is the domain whose subdomains name organizations, e.g.
+	// trikona.com for acme.trikona.com; empty disables subdomain resolution
+	BaseDomain string `env:"TENANT_BASE_DOMAIN"`
+}
+
+// Load reads the configuration from layered sources, applies defaults and
+// validates it. args are the command-line arguments without the program
+// name. Sources take precedence in this order, lowest first:
+//
+//  1. defaults declared on the struct
+//  2. the config file given by --config or CONFIG_FILE, or the first of
+//     configs/config.{yaml,yml,toml} that exists
+//  3. the environment-specific file next to it, e.g. configs/config.production.yaml
+//     for ENV=prod
+//  4. environment variables, including .env.<environment> and .env
+//  5. command-line flags named after the env keys, e.g. --db-host
+//
+// The returned error lists every problem found.
+func Load(args []string) (*Config, error) {
+	src, err := loadSources(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := decode(cfg, src.lookup); err != nil {
+		return nil, err
+	}
+	cfg.sources = src.used
+	cfg.files = src.files
+	cfg.applyDerivedDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyDerivedDefaults fills defaults that depend on other fields
+func (c *Config) applyDerivedDefaults() {
+	if env, err := environment.Parse(string(c.Env)); err == nil {
+		c.Env = env
+	}
+
+	if c.Database.SSLMode == "" {
+		c.Database.SSLMode = "disable"
+		if c.Env.IsProduction() {
+			c.Database.SSLMode = "require"
+		}
+	}
+
+	if c.Log.Level == "" {
+		c.Log.Level = logger.DefaultLevel(c.Env)
+	}
+}
+
+// Print writes the effective configuration with the source of each value,
+// redacting secrets
+func (c *Config) Print(w io.Writer) {
+	walk(reflect.ValueOf(c).Elem(), func(field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		source, ok := c.sources[key]
+		if !ok {
+			source = SourceDefault
+			if _, hasDefault := field.Tag.Lookup("default"); !hasDefault {
+				source = "unset"
+			}
+		}
+
+		shown := fmt.Sprint(value.Interface())
+		if items, ok := value.Interface().([]string); ok {
+			shown = strings.Join(items, ",")
+		}
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			shown = "******"
+		}
+		fmt.Fprintf(w, "%s=%s (%s)\n", key, shown, source)
+	})
+}
+
 // LoadEnv loads environment variables from .env file
 func LoadEnv() error {
 	return godotenv.Load(".env")
 }
 
 // GetEnv returns the value of the environment variable or the default value if not set
+//
+// Deprecated: declare the setting on Config and use Load instead.
 func GetEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -20,22 +263,11 @@ func GetEnv(key, defaultValue string) string {
 	return value
 }
 
-// LinkedInConfig holds LinkedIn OAuth configuration
-type LinkedInConfig struct {
-	ClientID     string
-	ClientSecret string
-	RedirectURI  string
-	TokenURL     string
-	ProfileURL   string
-}
-
 // LoadLinkedInConfig loads LinkedIn OAuth configuration from environment variables
+//
+// Deprecated: use the LinkedIn section of the Config returned by Load.
 func LoadLinkedInConfig() *LinkedInConfig {
-	return &LinkedInConfig{
-		ClientID:     os.Getenv("LINKEDIN_CLIENT_ID"),
-		ClientSecret: os.Getenv("LINKEDIN_CLIENT_SECRET"),
-		RedirectURI:  os.Getenv("LINKEDIN_REDIRECT_URI"),
-		TokenURL:     "https://www.linkedin.com/oauth/v2/accessToken",
-		ProfileURL:   "https://api.linkedin.com/v2/me?projection=(id,firstName,lastName,profilePicture(displayImage~:playableStreams))",
-	}
-} 
\ No newline at end of file
+	cfg := &LinkedInConfig{}
+	_ = decode(cfg, os.LookupEnv)
+	return cfg
+}
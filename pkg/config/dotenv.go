@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	dotenvMu sync.Mutex
+	// dotenvKeys are the variables set from .env files rather than by the
+	// real environment
+	dotenvKeys = map[string]bool{}
+)
+
+// loadDotEnv sets the variables of the .env files, earlier files taking
+// precedence, in the process environment. Variables of the real
+// environment are never overridden. Variables set by a previous call are
+// updated or unset to match the files, so a reload picks up edits.
+func loadDotEnv(files ...string) {
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+
+	values := map[string]string{}
+	for _, file := range files {
+		read, err := godotenv.Read(file)
+		if err != nil {
+			continue
+		}
+		for key, value := range read {
+			if _, ok := values[key]; !ok {
+				values[key] = value
+			}
+		}
+	}
+
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenvKeys[key] = true
+	}
+	for key := range dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenvKeys, key)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt")
+	writeFile(t, secretFile, "file-secret\n")
+	t.Setenv("OTHER_SECRET", "env-secret")
+	unsetenv(t, "MISSING_SECRET")
+	RegisterSecretProvider("test", SecretProviderFunc(func(_ context.Context, ref string) (string, error) {
+		if ref == "fail" {
+			return "", errors.New("unavailable")
+		}
+		return "test:" + ref, nil
+	}))
+
+	tests := []struct {
+		value, want, err string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "https://example.com", want: "https://example.com"},
+		{value: "file://" + secretFile, want: "file-secret"},
+		{value: "env://OTHER_SECRET", want: "env-secret"},
+		{value: "test://kv/app#key", want: "test:kv/app#key"},
+		{value: "file://" + filepath.Join(dir, "missing"), err: "failed to resolve file secret"},
+		{value: "env://MISSING_SECRET", err: "MISSING_SECRET is not set"},
+		{value: "test://fail", err: "unavailable"},
+	}
+	for _, tt := range tests {
+		got, err := resolveSecret(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("resolveSecret(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestEnvironFileConvention(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt")
+	writeFile(t, secretFile, "from-file\n")
+
+	unsetenv(t, "JWT_SECRET")
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("DB_PASSWORD_FILE", secretFile)
+
+	values := environ()
+	if values["JWT_SECRET"] != "file://"+secretFile {
+		t.Errorf("JWT_SECRET = %q, want a file reference", values["JWT_SECRET"])
+	}
+	if values["DB_PASSWORD"] != "from-env" {
+		t.Errorf("DB_PASSWORD = %q, want the variable to win over _FILE", values["DB_PASSWORD"])
+	}
+
+	var target struct {
+		Secret string `env:"JWT_SECRET"`
+	}
+	if err := decode(&target, func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if target.Secret != "from-file" {
+		t.Errorf("decoded JWT_SECRET = %q, want from-file", target.Secret)
+	}
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hacKRD0/trikona_go/pkg/environment"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the configuration sources, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnvFile = "env-file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// defaultConfigFiles are tried in order when no config file is given
+var defaultConfigFiles = []string{
+	"configs/config.yaml",
+	"configs/config.yml",
+	"configs/config.toml",
+}
+
+// layer is a configuration source mapping env keys to raw values
+type layer struct {
+	name   string
+	values map[string]string
+}
+
+// sources resolves settings across layers, highest precedence first, and
+// records which layer supplied each setting
+type sources struct {
+	layers []layer
+	used   map[string]string
+	// files lists the config and .env files that were considered, read or
+	// not
+	files []string
+}
+
+func (s *sources) lookup(key string) (string, bool) {
+	for _, l := range s.layers {
+		if v, ok := l.values[key]; ok && v != "" {
+			s.used[key] = l.name
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// loadSources builds the layers for the given command-line arguments:
+// defaults < config file < environment-specific config file < environment
+// (including .env files) < command-line flags
+func loadSources(args []string) (*sources, error) {
+	flags, configFile, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	// The environment profile selects the .env and config file overlays, so
+	// resolve it from the layers that can set it before loading them
+	env := profile(flags["ENV"])
+	if env == "" {
+		env = profile(os.Getenv("ENV"))
+	}
+
+	// The profile-specific file takes precedence over .env
+	envFiles := []string{".env"}
+	if env != "" {
+		envFiles = []string{".env." + env, ".env"}
+	}
+	loadDotEnv(envFiles...)
+	if env == "" {
+		env = profile(os.Getenv("ENV"))
+	}
+
+	var file map[string]string
+	files := append([]string(nil), envFiles...)
+	if configFile != "" {
+		files = append(files, configFile)
+		if file, err = readConfigFile(configFile); err != nil {
+			return nil, err
+		}
+	} else {
+		files = append(files, defaultConfigFiles...)
+		for _, candidate := range defaultConfigFiles {
+			if _, statErr := os.Stat(candidate); statErr == nil {
+				configFile = candidate
+				if file, err = readConfigFile(configFile); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	if env == "" {
+		env = profile(file["ENV"])
+	}
+	var envFile map[string]string
+	if configFile != "" && env != "" {
+		ext := filepath.Ext(configFile)
+		profileFile := strings.TrimSuffix(configFile, ext) + "." + env + ext
+		files = append(files, profileFile)
+		if _, statErr := os.Stat(profileFile); statErr == nil {
+			if envFile, err = readConfigFile(profileFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &sources{
+		layers: []layer{
+			{name: SourceFlag, values: flags},
+			{name: SourceEnv, values: environ()},
+			{name: SourceEnvFile, values: envFile},
+			{name: SourceFile, values: file},
+		},
+		used:  make(map[string]string),
+		files: files,
+	}, nil
+}
+
+// profile returns the canonical name of the environment, which names the
+// profile files, so that ENV=prod and ENV=Production both select
+// config.production.yaml. Unknown names are kept as is and rejected by
+// validation.
+func profile(env string) string {
+	if canonical, err := environment.Parse(env); err == nil {
+		return canonical.String()
+	}
+	return env
+}
+
+// parseFlags defines a flag for every setting, named after its env key
+// (DB_HOST becomes --db-host), plus --config for the config file path
+func parseFlags(args []string) (map[string]string, string, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file")
+
+	keys := map[string]string{}
+	walk(reflect.ValueOf(&Config{}).Elem(), func(field reflect.StructField, _ reflect.Value) {
+		key := field.Tag.Get("env")
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		keys[name] = key
+		fs.String(name, "", "overrides "+key)
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	values := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := keys[f.Name]; ok {
+			values[key] = f.Value.String()
+		}
+	})
+	return values, *configFile, nil
+}
+
+// environ returns the environment. Following the Docker and Kubernetes
+// secrets convention, KEY_FILE=/path supplies KEY from a file when KEY
+// itself is not set.
+func environ() map[string]string {
+	values := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			values[k] = v
+		}
+	}
+
+	for k, path := range values {
+		key, ok := strings.CutSuffix(k, "_FILE")
+		if !ok || path == "" || values[key] != "" {
+			continue
+		}
+		values[key] = "file://" + path
+	}
+	return values
+}
+
+// readConfigFile parses a YAML or TOML file whose nested keys follow the
+// Config struct in snake case, e.g. database.host or mail.from_email, and
+// returns the values keyed by env key
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	flat := map[string]string{}
+	flatten("", tree, flat)
+
+	values := map[string]string{}
+	var unknown []string
+	paths := filePaths()
+	for path, v := range flat {
+		key, ok := paths[path]
+		if !ok {
+			unknown = append(unknown, path)
+			continue
+		}
+		values[key] = v
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+func flatten(prefix string, node interface{}, out map[string]string) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, v, out)
+		}
+	case []interface{}:
+		items := make([]string, len(n))
+		for i, item := range n {
+			items[i] = fmt.Sprint(item)
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(n)
+	}
+}
+
+// filePaths maps the dotted snake-case file path of every setting to its env
+// key; a file tag overrides the name derived from a field
+func filePaths() map[string]string {
+	paths := map[string]string{}
+	var visit func(prefix string, t reflect.Type)
+	visit = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			path := snakeCase(field.Name)
+			if name, ok := field.Tag.Lookup("file"); ok {
+				path = name
+			}
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+
+			if key, ok := field.Tag.Lookup("env"); ok {
+				paths[path] = key
+			} else if field.Type.Kind() == reflect.Struct {
+				visit(path, field.Type)
+			}
+		}
+	}
+	visit("", reflect.TypeOf(Config{}))
+	return paths
+}
+
+// snakeCase converts a Go identifier to snake case, keeping acronyms
+// together: MailjetAPIKey becomes mailjet_api_key
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// unsetenv unsets key for the duration of the test
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSourcesPrecedence(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, key := range []string{"CONFIG_FILE", "DB_HOST", "DB_USER", "DB_NAME", "DB_SSLMODE", "LOG_LEVEL", "PORT"} {
+		unsetenv(t, key)
+	}
+	t.Setenv("ENV", "staging")
+	t.Setenv("DB_PORT", "5434")
+
+	writeFile(t, "configs/config.yaml", `
+database:
+  host: file-host
+  port: 5433
+  user: file-user
+  name: file-name
+log:
+  level: warn
+`)
+	writeFile(t, "configs/config.staging.yaml", `
+database:
+  host: profile-host
+  port: 5435
+  ssl_mode: require
+`)
+	writeFile(t, ".env", "DB_USER=dotenv-user\n")
+
+	src, err := loadSources([]string{"--db-host", "flag-host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, source string
+	}{
+		{"DB_HOST", "flag-host", SourceFlag},
+		{"DB_PORT", "5434", SourceEnv},
+		{"DB_USER", "dotenv-user", SourceEnv},
+		{"DB_SSLMODE", "require", SourceEnvFile},
+		{"DB_NAME", "file-name", SourceFile},
+		{"LOG_LEVEL", "warn", SourceFile},
+	}
+	for _, tt := range tests {
+		value, ok := src.lookup(tt.key)
+		if !ok || value != tt.value || src.used[tt.key] != tt.source {
+			t.Errorf("%s = %q from %q, want %q from %q", tt.key, value, src.used[tt.key], tt.value, tt.source)
+		}
+	}
+	if value, ok := src.lookup("PORT"); ok {
+		t.Errorf("PORT = %q, want unset", value)
+	}
+}
+
+func TestLoadSourcesConfigFlag(t *testing.T) {
+	t.Chdir(t.TempDir())
+	unsetenv(t, "CONFIG_FILE")
+	unsetenv(t, "ENV")
+	unsetenv(t, "DB_HOST")
+
+	writeFile(t, "configs/config.yaml", "database:\n  host: default-file\n")
+	writeFile(t, "other.toml", "[database]\nhost = \"toml-file\"\n")
+
+	src, err := loadSources([]string{"--config", "other.toml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := src.lookup("DB_HOST"); value != "toml-file" {
+		t.Errorf("DB_HOST = %q, want toml-file", value)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content string
+		want          map[string]string
+		err           string
+	}{
+		{
+			name:    "config.yaml",
+			content: "mail:\n  from_email: a@example.com\nlinkedin:\n  client_id: abc\ncors:\n  allow_origins: [a, b]\n",
+			want:    map[string]string{"MAILJET_FROM_EMAIL": "a@example.com", "LINKEDIN_CLIENT_ID": "abc", "CORS_ALLOW_ORIGINS": "a,b"},
+		},
+		{
+			name:    "config.toml",
+			content: "[mail]\nmailjet_api_key = \"key\"\n",
+			want:    map[string]string{"MAILJET_API_KEY": "key"},
+		},
+		{name: "unknown.yaml", content: "database:\n  hots: x\n", err: "unknown keys"},
+		{name: "config.json", content: "{}", err: "unsupported config file format"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		writeFile(t, path, tt.content)
+		got, err := readConfigFile(path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for key, value := range tt.want {
+			if got[key] != value {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got[key], value)
+			}
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Host":          "host",
+		"FromEmail":     "from_email",
+		"MailjetAPIKey": "mailjet_api_key",
+		"SSLMode":       "ssl_mode",
+		"FrontendURL":   "frontend_url",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks required fields, field formats and cross-field rules
+func (c *Config) Validate() error {
+	var problems []string
+	walk(reflect.ValueOf(c).Elem(), func(field reflect.StructField, value reflect.Value) {
+		problems = append(problems, validateField(field, value)...)
+	})
+	problems = append(problems, c.Database.validate()...)
+	problems = append(problems, c.Mail.validate()...)
+	problems = append(problems, c.Account.validate()...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validate checks that either DATABASE_URL or the individual connection
+// settings are given
+func (c *DatabaseConfig) validate() []string {
+	var problems []string
+	if c.MaxIdleConns > c.MaxOpenConns {
+		problems = append(problems, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	}
+
+	if c.LogSampleRate < 0 || c.LogSampleRate > 1 {
+		problems = append(problems, "DB_LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		problems = append(problems, "DB_SSLCERT and DB_SSLKEY must be set together")
+	}
+	for _, file := range []struct{ key, path string }{
+		{"DB_SSLROOTCERT", c.SSLRootCert},
+		{"DB_SSLCERT", c.SSLCert},
+		{"DB_SSLKEY", c.SSLKey},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file.key, err))
+		}
+	}
+	if c.SSLMode == "disable" && (c.SSLRootCert != "" || c.SSLCert != "") {
+		problems = append(problems, "DB_SSLROOTCERT and DB_SSLCERT require DB_SSLMODE other than disable")
+	}
+
+	for i, replica := range c.ReplicaURLs {
+		u, err := url.Parse(replica)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			problems = append(problems, fmt.Sprintf("DB_REPLICA_URLS entry %d must be a postgres:// URL", i+1))
+		}
+	}
+	if len(c.ReplicaURLs) > 0 && c.ReplicaHealthInterval <= 0 {
+		problems = append(problems, "DB_REPLICA_HEALTH_INTERVAL must be positive when DB_REPLICA_URLS is set")
+	}
+
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			problems = append(problems, "DATABASE_URL must be a postgres:// URL")
+		}
+		return problems
+	}
+
+	if c.Host == "" {
+		problems = append(problems, "DB_HOST is required when DATABASE_URL is not set")
+	}
+	if c.User == "" {
+		problems = append(problems, "DB_USER is required when DATABASE_URL is not set")
+	}
+	if c.Name == "" {
+		problems = append(problems, "DB_NAME is required when DATABASE_URL is not set")
+	}
+	return problems
+}
+
+// validate checks the settings required by the selected transport
+func (c *MailConfig) validate() []string {
+	var problems []string
+	switch c.Transport {
+	case "mailjet":
+		if c.MailjetAPIKey == "" {
+			problems = append(problems, "MAILJET_API_KEY is required when MAILER_TRANSPORT is mailjet")
+		}
+		if c.MailjetSecretKey == "" {
+			problems = append(problems, "MAILJET_SECRET_KEY is required when MAILER_TRANSPORT is mailjet")
+		}
+	case "smtp":
+		if c.SMTPHost == "" {
+			problems = append(problems, "SMTP_HOST is required when MAILER_TRANSPORT is smtp")
+		}
+	case "file":
+		if c.FileDir == "" {
+			problems = append(problems, "MAILER_FILE_DIR is required when MAILER_TRANSPORT is file")
+		}
+	}
+	return problems
+}
+
+// validate checks the account lifecycle durations
+func (c *AccountConfig) validate() []string {
+	var problems []string
+	if c.RestoreWindow < 0 {
+		problems = append(problems, "ACCOUNT_RESTORE_WINDOW must not be negative")
+	}
+	if c.PurgeInterval <= 0 {
+		problems = append(problems, "ACCOUNT_PURGE_INTERVAL must be positive")
+	}
+	return problems
+}
+
+func validateField(field reflect.StructField, value reflect.Value) []string {
+	key := field.Tag.Get("env")
+	if value.IsZero() {
+		if field.Tag.Get("required") == "true" {
+			return []string{key + " is required"}
+		}
+		return nil
+	}
+
+	var problems []string
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "" {
+			continue
+		}
+		if err := checkRule(rule, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %v", key, err))
+		}
+	}
+	return problems
+}
+
+func checkRule(rule string, value reflect.Value) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	s := fmt.Sprint(value.Interface())
+
+	switch name {
+	case "url":
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("must be an absolute http(s) URL")
+		}
+	case "email":
+		if _, err := mail.ParseAddress(s); err != nil {
+			return fmt.Errorf("must be a valid email address")
+		}
+	case "port":
+		if p, err := strconv.Atoi(s); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("must be a port between 1 and 65535")
+		}
+	case "oneof":
+		for _, allowed := range strings.Fields(arg) {
+			if s == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(strings.Fields(arg), ", "))
+	case "min":
+		n, _ := strconv.Atoi(arg)
+		if value.Kind() == reflect.String && len(s) < n {
+			return fmt.Errorf("must be at least %d characters long", n)
+		}
+		if value.Kind() != reflect.String {
+			if f, err := strconv.ParseFloat(s, 64); err == nil && f < float64(n) {
+				return fmt.Errorf("must be at least %d", n)
+			}
+		}
+	default:
+		return fmt.Errorf("has unknown validation rule %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultWatchInterval is how often watched config files are polled for changes
+const DefaultWatchInterval = 2 * time.Second
+
+// Watcher holds the current configuration and reloads it when a config or
+// .env file changes or the process receives SIGHUP. A reload that fails to load or
+// validate is logged and the current configuration is kept.
+//
+// Only settings tagged reload:"true" take effect without a restart, and only
+// once a subscriber applies them. Changes to other settings are logged as
+// requiring a restart, and Config keeps returning the values the process
+// started with for them.
+type Watcher struct {
+	args     []string
+	interval time.Duration
+	current  atomic.Pointer[Config]
+
+	// reloading serializes reloads, so subscribers see configurations in
+	// the order they were swapped in
+	reloading sync.Mutex
+
+	mu          sync.Mutex
+	subscribers []func(*Config)
+	stamps      map[string]string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatcher loads the configuration for the given command-line arguments,
+// as Load does, and returns a watcher holding it
+func NewWatcher(args []string) (*Watcher, error) {
+	cfg, err := Load(args)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		args:     args,
+		interval: DefaultWatchInterval,
+	}
+	w.current.Store(cfg)
+	w.stamps = fileStamps(cfg.files)
+	return w, nil
+}
+
+// Config returns the current configuration. It must not be modified.
+func (w *Watcher) Config() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called with the new configuration after every
+// successful reload. Subscribers are called in registration order.
+func (w *Watcher) Subscribe(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Reload loads and validates the configuration and, if it is valid, swaps it
+// in and notifies subscribers. Reloads run one at a time, so subscribers are
+// notified in order; they may call Config and Subscribe, but not Reload.
+func (w *Watcher) Reload() error {
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	w.mu.Lock()
+	cfg, changed, err := w.reload()
+	subscribers := make([]func(*Config), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mu.Unlock()
+	if err != nil || !changed {
+		return err
+	}
+
+	for _, fn := range subscribers {
+		fn(cfg)
+	}
+	return nil
+}
+
+// reload swaps in the new configuration and reports whether any setting
+// that can be applied live changed
+func (w *Watcher) reload() (*Config, bool, error) {
+	cfg, err := Load(w.args)
+	if err != nil {
+		return nil, false, err
+	}
+	w.stamps = fileStamps(cfg.files)
+
+	old := w.Config()
+	live, restart := changedKeys(old, cfg)
+	if len(restart) > 0 {
+		logger.Warn("Configuration changes require a restart",
+			zap.Strings("keys", restart),
+		)
+	}
+	keepRestartSettings(old, cfg)
+	w.current.Store(cfg)
+	if len(live) == 0 {
+		return cfg, false, nil
+	}
+	logger.Info("Configuration reloaded", zap.Strings("keys", live))
+	return cfg, true, nil
+}
+
+// keepRestartSettings copies the settings that are not tagged reload from
+// old to new, so new only differs in the settings that apply live
+func keepRestartSettings(old, new *Config) {
+	previous := map[string]reflect.Value{}
+	walk(reflect.ValueOf(old).Elem(), func(field reflect.StructField, value reflect.Value) {
+		previous[field.Tag.Get("env")] = value
+	})
+
+	walk(reflect.ValueOf(new).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("reload") != "true" {
+			value.Set(previous[field.Tag.Get("env")])
+		}
+	})
+}
+
+// Start watches the config and .env files and SIGHUP until Stop is called
+func (w *Watcher) Start() {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer close(w.done)
+		defer signal.Stop(hup)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-hup:
+				w.reloadAndLog("SIGHUP")
+			case <-ticker.C:
+				if w.filesChanged() {
+					w.reloadAndLog("config file changed")
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops watching and waits for an in-flight reload to finish
+func (w *Watcher) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+	w.stop = nil
+}
+
+func (w *Watcher) reloadAndLog(trigger string) {
+	if err := w.Reload(); err != nil {
+		logger.Error("Rejected configuration reload", err, zap.String("trigger", trigger))
+	}
+}
+
+// filesChanged reports whether the watched files changed since the last
+// check, so a rejected edit is reported once rather than on every poll
+func (w *Watcher) filesChanged() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stamps := fileStamps(w.Config().files)
+	if reflect.DeepEqual(stamps, w.stamps) {
+		return false
+	}
+	w.stamps = stamps
+	return true
+}
+
+// fileStamps returns the modification time and size of each file, so that
+// files being created, edited or removed are all noticed
+func fileStamps(files []string) map[string]string {
+	stamps := make(map[string]string, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			stamps[file] = "missing"
+			continue
+		}
+		stamps[file] = fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+	}
+	return stamps
+}
+
+// changedKeys returns the env keys of the settings that differ between old
+// and new, split into those that can be applied live and those that need a
+// restart
+func changedKeys(old, new *Config) (live, restart []string) {
+	previous := map[string]interface{}{}
+	walk(reflect.ValueOf(old).Elem(), func(field reflect.StructField, value reflect.Value) {
+		previous[field.Tag.Get("env")] = value.Interface()
+	})
+
+	walk(reflect.ValueOf(new).Elem(), func(field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		if reflect.DeepEqual(previous[key], value.Interface()) {
+			return
+		}
+		if field.Tag.Get("reload") == "true" {
+			live = append(live, key)
+		} else {
+			restart = append(restart, key)
+		}
+	})
+
+	sort.Strings(live)
+	sort.Strings(restart)
+	return live, restart
+}
+
+// HealthCheck returns a health check that fails unless current returns a
+// loaded, valid configuration. Pass Watcher.Config, or a closure over the
+// Config returned by Load.
+func HealthCheck(current func() *Config) func(ctx context.Context) error {
+	return func(context.Context) error {
+		cfg := current()
+		if cfg == nil {
+			return fmt.Errorf("configuration is not loaded")
+		}
+		return cfg.Validate()
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestWatcherReload(t *testing.T) {
+	logger.Log = zap.NewNop()
+	t.Chdir(t.TempDir())
+	for _, key := range []string{"CONFIG_FILE", "ENV", "LOG_LEVEL", "PORT"} {
+		unsetenv(t, key)
+	}
+	t.Setenv("FRONTEND_URL", "https://example.com")
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("MAILJET_FROM_EMAIL", "noreply@example.com")
+	t.Setenv("UNSUBSCRIBE_SECRET", "0123456789abcdef")
+	t.Setenv("DATABASE_URL", "postgres://localhost/trikona")
+	t.Setenv("MAILER_TRANSPORT", "memory")
+
+	writeFile(t, "configs/config.yaml", "log:\n  level: info\nserver:\n  port: 8080\n")
+	w, err := NewWatcher(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified []string
+	w.Subscribe(func(cfg *Config) {
+		notified = append(notified, cfg.Log.Level)
+	})
+
+	writeFile(t, "configs/config.yaml", "log:\n  level: debug\nserver:\n  port: 9090\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := w.Config(); cfg.Log.Level != "debug" || cfg.Server.Port != 8080 {
+		t.Errorf("after reload LOG_LEVEL = %q, PORT = %d, want debug, 8080", cfg.Log.Level, cfg.Server.Port)
+	}
+
+	// A change that only needs a restart does not notify subscribers
+	writeFile(t, "configs/config.yaml", "log:\n  level: debug\nserver:\n  port: 9091\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != "debug" {
+		t.Errorf("subscriber notified with %q, want [debug]", notified)
+	}
+	if port := w.Config().Server.Port; port != 8080 {
+		t.Errorf("after restart-only reload PORT = %d, want 8080", port)
+	}
+}
@@ -1,54 +1,256 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"embed"
 	"fmt"
-	"log"
-	"os"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/hacKRD0/trikona_go/internal/user-management-service/domain"
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/migrate"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// InitDB initializes the database connection
-func InitDB() (*gorm.DB, error) {
-	// Construct the DSN
-	env := os.Getenv("ENV")
-	sslmode := "disable"
-	if env == "prod" {
-		sslmode = "require"
-	}
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-		sslmode,
-	)
+//go:embed migrations/*.sql
+var migrations embed.FS
 
-	// Open the database connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Migrations returns the embedded schema migrations
+func Migrations() fs.FS {
+	sub, err := fs.Sub(migrations, "migrations")
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+		panic(err)
+	}
+	return sub
+}
+
+// retry backoff bounds for connecting at startup
+const (
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
+
+// InitDB initializes the database connection pool. While Postgres is not
+// accepting connections yet, e.g. during a docker-compose boot, it retries
+// with exponential backoff until cfg.ConnectTimeout; a zero timeout makes
+// a single attempt. The schema is managed by migrations; see Migrate.
+func InitDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.ConnectTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	}
+	defer cancel()
 
-	// Auto migrate all the database schemas
-	err = db.AutoMigrate(&domain.User{})
+	// Open the database connection
+	db, err := gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{
+		Logger: NewQueryLogger(cfg),
+		// The connection is checked below, with retries
+		DisableAutomaticPing: true,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to auto migrate database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
-	// Test the connection
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database instance: %v", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %v", err)
+	// Test the connection, retrying until the deadline
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			break
+		}
+
+		if ctx.Err() != nil || cfg.ConnectTimeout <= 0 {
+			sqlDB.Close()
+			return nil, fmt.Errorf("failed to connect to database after %d attempts: %v", attempt, err)
+		}
+		logger.Warn("Database not reachable, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			sqlDB.Close()
+			return nil, fmt.Errorf("failed to connect to database after %d attempts: %v", attempt, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
 	}
 
-	log.Println("Successfully connected to database")
+	if len(cfg.ReplicaURLs) > 0 {
+		if err := useReplicas(db, cfg); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	logger.Info("Successfully connected to database")
 	return db, nil
-} 
\ No newline at end of file
+}
+
+// useReplicas opens the replica pools and installs the Resolver. Replicas
+// that are down are left out of rotation until their health check passes.
+func useReplicas(db *gorm.DB, cfg *config.DatabaseConfig) error {
+	replicas := make(map[string]*sql.DB, len(cfg.ReplicaURLs))
+	closeAll := func() {
+		for _, r := range replicas {
+			r.Close()
+		}
+	}
+
+	for _, replicaURL := range cfg.ReplicaURLs {
+		replicaDB, err := gorm.Open(postgres.Open(withTLS(replicaURL, cfg)), &gorm.Config{
+			Logger:               NewQueryLogger(cfg),
+			DisableAutomaticPing: true,
+		})
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to open read replica: %v", err)
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to get read replica instance: %v", err)
+		}
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+		name := replicaName(replicaURL)
+		if _, dup := replicas[name]; dup {
+			name = fmt.Sprintf("%s#%d", name, len(replicas)+1)
+		}
+		replicas[name] = sqlDB
+	}
+
+	if err := db.Use(NewResolver(replicas, cfg.ReplicaHealthInterval, cfg.ReplicaMaxLag)); err != nil {
+		closeAll()
+		return fmt.Errorf("failed to install read replica resolver: %v", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool returned by InitDB, including any read
+// replica pools
+func Close(db *gorm.DB) error {
+	if plugin, ok := db.Config.Plugins[(&Resolver{}).Name()]; ok {
+		if err := plugin.(*Resolver).Close(); err != nil {
+			logger.Error("Failed to close read replicas", err)
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %v", err)
+	}
+	return sqlDB.Close()
+}
+
+// replicaName identifies a replica in logs by host and port only, since its
+// URL may hold credentials
+func replicaName(replicaURL string) string {
+	u, err := url.Parse(replicaURL)
+	if err != nil || u.Host == "" {
+		return "replica"
+	}
+	return u.Host
+}
+
+// DSN returns the connection string for cfg. With DATABASE_URL set, the
+// URL is used and the TLS settings are added unless it already has them;
+// otherwise a keyword/value string is built with every value quoted and
+// escaped, so passwords containing spaces, quotes or backslashes work.
+func DSN(cfg *config.DatabaseConfig) string {
+	if cfg.URL != "" {
+		return withTLS(cfg.URL, cfg)
+	}
+
+	params := append([]struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", strconv.Itoa(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+	}, tlsParams(cfg)...)
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// withTLS adds the TLS settings of cfg to a connection URL unless it
+// already has them
+func withTLS(rawURL string, cfg *config.DatabaseConfig) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+	for _, p := range tlsParams(cfg) {
+		if p.value != "" && !query.Has(p.key) {
+			query.Set(p.key, p.value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func tlsParams(cfg *config.DatabaseConfig) []struct{ key, value string } {
+	return []struct{ key, value string }{
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"sslpassword", cfg.SSLPassword},
+	}
+}
+
+// quoteDSNValue quotes a keyword/value connection string value as libpq
+// expects: single quotes, with backslashes and quotes escaped
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// NewMigrator creates a migrator for the embedded migrations
+func NewMigrator(db *gorm.DB, opts migrate.Options) (*migrate.Migrator, error) {
+	return migrate.New(db, Migrations(), opts)
+}
+
+// Migrate applies every pending embedded migration. It is safe to call from
+// several replicas at once.
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	migrator, err := NewMigrator(db, migrate.Options{})
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hacKRD0/trikona_go/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// HealthCheck returns a health check that pings the primary database
+func HealthCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get database instance: %v", err)
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationsCheck returns a health check that fails while embedded
+// migrations are pending or an applied migration has been modified
+func MigrationsCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		migrator, err := NewMigrator(db, migrate.Options{})
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		var pending, modified []string
+		for _, status := range statuses {
+			switch {
+			case !status.Applied:
+				pending = append(pending, status.Migration.String())
+			case status.Modified:
+				modified = append(modified, status.Migration.String())
+			}
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+		}
+		if len(modified) > 0 {
+			return fmt.Errorf("modified migrations: %s", strings.Join(modified, ", "))
+		}
+		return nil
+	}
+}
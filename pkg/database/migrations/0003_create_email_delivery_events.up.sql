@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS email_delivery_events (
+    id UUID PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    email VARCHAR(320) NOT NULL,
+    provider_message_id VARCHAR(100),
+    idempotency_key VARCHAR(255),
+    hard_bounce BOOLEAN,
+    error VARCHAR(255),
+    error_related_to VARCHAR(50),
+    source VARCHAR(50),
+    url TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    raw JSONB,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_delivery_events_type ON email_delivery_events (type);
+CREATE INDEX IF NOT EXISTS idx_email_delivery_events_email ON email_delivery_events (email);
+CREATE INDEX IF NOT EXISTS idx_email_delivery_events_provider_message_id ON email_delivery_events (provider_message_id);
+CREATE INDEX IF NOT EXISTS idx_email_delivery_events_idempotency_key ON email_delivery_events (idempotency_key);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    email VARCHAR(320) PRIMARY KEY,
+    reason VARCHAR(50) NOT NULL,
+    source VARCHAR(50),
+    details TEXT,
+    created_at TIMESTAMPTZ
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_purge_after;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS purge_after,
+    DROP COLUMN IF EXISTS deactivated_at;
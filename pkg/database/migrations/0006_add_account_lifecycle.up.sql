@@ -0,0 +1,10 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS purge_after TIMESTAMPTZ;
+
+-- Accounts deleted before the restore window existed get the default
+-- 30 day window from their deletion
+UPDATE users SET purge_after = deleted_at + INTERVAL '30 days'
+WHERE deleted_at IS NOT NULL AND purge_after IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after ON users (purge_after) WHERE purge_after IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_tenant_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS organizations;
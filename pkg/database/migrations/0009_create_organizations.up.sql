@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('company', 'institution')),
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_slug ON organizations (slug);
+
+-- Users outside any organization keep a NULL tenant
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tenant_id BIGINT REFERENCES organizations (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users (tenant_id) WHERE tenant_id IS NOT NULL;
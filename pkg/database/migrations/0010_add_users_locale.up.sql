@@ -0,0 +1,3 @@
+-- The user's preferred locale, e.g. hi-IN; NULL negotiates from the
+-- Accept-Language header
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35);
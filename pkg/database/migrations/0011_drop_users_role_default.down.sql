@@ -0,0 +1,2 @@
+-- Roles changed to 'guest' by the up migration are left as they are
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
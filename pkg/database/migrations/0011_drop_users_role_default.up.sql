@@ -0,0 +1,6 @@
+-- The baseline defaulted role to 'user', which validation.ValidateRole
+-- rejects. Callers always set the role, so there is no default; rows that
+-- got the invalid one become guests.
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+
+UPDATE users SET role = 'guest' WHERE role = 'user';
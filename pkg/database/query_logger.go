@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// queryLogLevels maps DB_LOG_LEVEL values to gorm log levels
+var queryLogLevels = map[string]gormlogger.LogLevel{
+	"silent": gormlogger.Silent,
+	"error":  gormlogger.Error,
+	"warn":   gormlogger.Warn,
+	"info":   gormlogger.Info,
+}
+
+// QueryLogger is a gorm logger writing to the zap logger of pkg/logger.
+// Failed queries are logged as errors and slow queries as warnings; other
+// queries are sampled at the info level. Entries carry the request ID of
+// the query's context, and query parameters are replaced by placeholders
+// unless enabled.
+type QueryLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	sampleRate    float64
+	logParams     bool
+}
+
+// NewQueryLogger creates a query logger from the database configuration
+func NewQueryLogger(cfg *config.DatabaseConfig) *QueryLogger {
+	level, ok := queryLogLevels[cfg.LogLevel]
+	if !ok {
+		level = gormlogger.Warn
+	}
+	return &QueryLogger{
+		level:         level,
+		slowThreshold: cfg.SlowQueryThreshold,
+		sampleRate:    cfg.LogSampleRate,
+		logParams:     cfg.LogParams,
+	}
+}
+
+// LogMode implements gormlogger.Interface
+func (l *QueryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// Info implements gormlogger.Interface
+func (l *QueryLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		logger.FromContext(ctx).Info(fmt.Sprintf(msg, data...), zap.String("source", querySource()))
+	}
+}
+
+// Warn implements gormlogger.Interface
+func (l *QueryLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		logger.FromContext(ctx).Warn(fmt.Sprintf(msg, data...), zap.String("source", querySource()))
+	}
+}
+
+// Error implements gormlogger.Interface
+func (l *QueryLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		logger.FromContext(ctx).Error(fmt.Sprintf(msg, data...), zap.String("source", querySource()))
+	}
+}
+
+// Trace implements gormlogger.Interface by logging a finished query
+func (l *QueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	fields := func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("duration", elapsed),
+			zap.String("source", querySource()),
+		}
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		logger.FromContext(ctx).Error("Query failed", append(fields(), zap.Error(err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		logger.FromContext(ctx).Warn("Slow query", append(fields(), zap.Duration("threshold", l.slowThreshold))...)
+	case l.level >= gormlogger.Info && l.sampled():
+		logger.FromContext(ctx).Info("Query", fields()...)
+	}
+}
+
+// redacted stands in for a query parameter in the query log
+type redacted struct{}
+
+// String implements fmt.Stringer
+func (redacted) String() string {
+	return "***"
+}
+
+// ParamsFilter implements gorm.ParamsFilter by masking the parameters in
+// the logged SQL unless LogParams is enabled
+func (l *QueryLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.logParams {
+		return sql, params
+	}
+	masked := make([]interface{}, len(params))
+	for i := range masked {
+		masked[i] = redacted{}
+	}
+	return sql, masked
+}
+
+func (l *QueryLogger) sampled() bool {
+	return l.sampleRate >= 1 || (l.sampleRate > 0 && rand.Float64() < l.sampleRate)
+}
+
+// querySource returns the file and line of the code that issued the query,
+// skipping gorm and this package
+func querySource() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.Contains(frame.File, "/pkg/database/") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// replicaLagQuery returns the replication delay of a standby in seconds, or
+// 0 when it has replayed everything it received
+const replicaLagQuery = `SELECT CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// defaultHealthInterval is used when NewResolver is given a non-positive
+// interval, which time.NewTicker would reject
+const defaultHealthInterval = 10 * time.Second
+
+type routingKey struct{}
+
+// routing tracks, for one request, whether reads must go to the primary
+type routing struct {
+	primary atomic.Bool
+}
+
+// WithReadYourWrites returns a context in which reads go to the primary as
+// soon as a write is made through it, so a request sees its own changes
+// even while the replicas catch up
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routingKey{}).(*routing); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routingKey{}, &routing{})
+}
+
+// UsePrimary returns a context in which every query goes to the primary
+func UsePrimary(ctx context.Context) context.Context {
+	r := &routing{}
+	r.primary.Store(true)
+	return context.WithValue(ctx, routingKey{}, r)
+}
+
+// replica is a read replica connection pool and its last health check
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Resolver is a gorm plugin that sends reads to healthy read replicas and
+// everything else to the primary. Reads fall back to the primary when no
+// replica is healthy, inside transactions, for locking reads, and after a
+// write within a WithReadYourWrites context.
+type Resolver struct {
+	replicas []*replica
+	interval time.Duration
+	maxLag   time.Duration
+	primary  gorm.ConnPool
+	next     atomic.Uint64
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewResolver creates a resolver over the given replica pools. Replicas are
+// health checked every interval and taken out of rotation while they are
+// unreachable or more than maxLag behind; a zero maxLag disables the lag check.
+// A non-positive interval falls back to defaultHealthInterval.
+func NewResolver(replicas map[string]*sql.DB, interval, maxLag time.Duration) *Resolver {
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	r := &Resolver{
+		interval: interval,
+		maxLag:   maxLag,
+		stop:     make(chan struct{}),
+	}
+	for name, db := range replicas {
+		r.replicas = append(r.replicas, &replica{name: name, db: db})
+	}
+	return r
+}
+
+// Name implements gorm.Plugin
+func (r *Resolver) Name() string {
+	return "database:resolver"
+}
+
+// Initialize implements gorm.Plugin by registering the routing callbacks and
+// starting the health checks
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+
+	route := r.route
+	if err := db.Callback().Query().Before("gorm:query").Register("database:route", route); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("database:route", route); err != nil {
+		return err
+	}
+
+	sticky := r.markWrite
+	if err := db.Callback().Create().Before("gorm:create").Register("database:sticky", sticky); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("database:sticky", sticky); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("database:sticky", sticky); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("database:sticky", sticky); err != nil {
+		return err
+	}
+
+	r.done.Add(1)
+	go r.healthLoop()
+	return nil
+}
+
+// Close stops the health checks and closes the replica pools. Later calls
+// return the result of the first.
+func (r *Resolver) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.done.Wait()
+
+		for _, rep := range r.replicas {
+			if err := rep.db.Close(); err != nil && r.closeErr == nil {
+				r.closeErr = err
+			}
+		}
+	})
+	return r.closeErr
+}
+
+// route points read statements at a healthy replica
+func (r *Resolver) route(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt.ConnPool != r.primary || !isRead(stmt) {
+		return
+	}
+	if rt, ok := stmt.Context.Value(routingKey{}).(*routing); ok && rt.primary.Load() {
+		return
+	}
+
+	if rep := r.pick(); rep != nil {
+		stmt.ConnPool = rep.db
+	}
+}
+
+// markWrite makes later reads in the same request go to the primary
+func (r *Resolver) markWrite(db *gorm.DB) {
+	if rt, ok := db.Statement.Context.Value(routingKey{}).(*routing); ok {
+		rt.primary.Store(true)
+	}
+}
+
+// pick returns the next healthy replica in round-robin order, or nil
+func (r *Resolver) pick() *replica {
+	n := len(r.replicas)
+	start := int(r.next.Add(1))
+	for i := 0; i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// isRead reports whether a statement can be served by a replica: a raw
+// SELECT or a query without a locking clause
+func isRead(stmt *gorm.Statement) bool {
+	if _, locking := stmt.Clauses["FOR"]; locking {
+		return false
+	}
+	if stmt.SQL.Len() == 0 {
+		return true
+	}
+
+	query := strings.ToUpper(strings.TrimSpace(stmt.SQL.String()))
+	return strings.HasPrefix(query, "SELECT") && !strings.Contains(query, " FOR UPDATE") && !strings.Contains(query, " FOR SHARE")
+}
+
+func (r *Resolver) healthLoop() {
+	defer r.done.Done()
+
+	r.checkAll()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkAll()
+		}
+	}
+}
+
+func (r *Resolver) checkAll() {
+	for _, rep := range r.replicas {
+		err := r.check(rep)
+		healthy := err == nil
+		if rep.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		if healthy {
+			logger.Info("Read replica is healthy", zap.String("replica", rep.name))
+		} else {
+			logger.Warn("Read replica is unhealthy, reads fall back to the primary",
+				zap.String("replica", rep.name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// check pings a replica and, when maxLag is set, checks its replication lag
+func (r *Resolver) check(rep *replica) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	if err := rep.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if r.maxLag <= 0 {
+		return nil
+	}
+
+	var lag float64
+	if err := rep.db.QueryRowContext(ctx, replicaLagQuery).Scan(&lag); err != nil {
+		return err
+	}
+	if behind := time.Duration(lag * float64(time.Second)); behind > r.maxLag {
+		return fmt.Errorf("replica is %v behind, more than %v", behind.Round(time.Millisecond), r.maxLag)
+	}
+	return nil
+}
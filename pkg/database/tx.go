@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Postgres error codes of transient conflicts that succeed when the whole
+// transaction is retried
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+type txKey struct{}
+
+// TxOptions configures a unit of work
+type TxOptions struct {
+	// Isolation is the transaction isolation level; the zero value uses
+	// the database default (read committed)
+	Isolation sql.IsolationLevel
+	// ReadOnly starts a read-only transaction
+	ReadOnly bool
+	// MaxAttempts is the number of times the transaction is run when it
+	// fails with a serialization failure or deadlock
+	MaxAttempts int
+	// RetryDelay is the delay before the first retry; it doubles, with
+	// jitter, on every further retry
+	RetryDelay time.Duration
+}
+
+// DefaultTxOptions returns the options used by WithTx
+func DefaultTxOptions() TxOptions {
+	return TxOptions{
+		MaxAttempts: 3,
+		RetryDelay:  20 * time.Millisecond,
+	}
+}
+
+// WithTx runs fn as a unit of work in a transaction on db, committing if fn
+// returns nil and rolling back otherwise. The transaction travels in the
+// context passed to fn, so repositories using Conn join it.
+//
+// Called inside another unit of work, WithTx runs fn in a savepoint of the
+// ambient transaction, which is rolled back on its own if fn fails.
+// Serialization failures and deadlocks of the outermost transaction are
+// retried, so fn may run more than once and must not have side effects
+// outside the database.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	return WithTxOptions(ctx, db, DefaultTxOptions(), fn)
+}
+
+// WithTxOptions is WithTx with explicit options. Options of a nested unit
+// of work are ignored in favour of the ambient transaction's.
+func WithTxOptions(ctx context.Context, db *gorm.DB, opts TxOptions, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	if ambient, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		// gorm runs a transaction begun on a transaction as a savepoint
+		return ambient.Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx), tx)
+		})
+	}
+
+	txOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	delay := opts.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx), tx)
+		}, txOpts)
+		if err == nil || !IsRetryable(err) || attempt >= opts.MaxAttempts {
+			return err
+		}
+
+		logger.Warn("Retrying transaction after conflict",
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay/2 + time.Duration(rand.Int63n(int64(delay)+1))):
+		}
+		delay *= 2
+	}
+}
+
+// Conn returns the ambient transaction of ctx, or db bound to ctx when
+// there is none. Repositories call it so that they take part in a unit of
+// work without being passed the transaction. A db that already is a
+// transaction is used as is.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return db.WithContext(ctx)
+	}
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// InTx reports whether ctx carries a transaction started by WithTx
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return ok
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock,
+// after which the whole transaction can be retried
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == codeSerializationFailure || pgErr.Code == codeDeadlockDetected
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// versionColumn is the optimistic locking column of versioned models
+const versionColumn = "version"
+
+// Version is embedded in models that use optimistic concurrency control. The
+// column starts at 1 and UpdateVersioned increments it on every update.
+type Version struct {
+	Version int64 `gorm:"not null;default:1" json:"version"`
+}
+
+// Versioned is implemented by the models embedding Version
+type Versioned interface {
+	versioned()
+}
+
+func (Version) versioned() {}
+
+// ETag returns the entity tag of the version, for the ETag header
+func (v Version) ETag() string {
+	return FormatETag(v.Version)
+}
+
+// FormatETag returns the strong entity tag of a version
+func FormatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// UpdateVersioned applies updates to the record of model, a pointer to a
+// model embedding Version with its primary key set, provided the record is
+// still at the expected version. The version is incremented and model is
+// refreshed from the updated row.
+//
+// When another update got there first, UpdateVersioned returns a conflict
+// error whose current_version meta holds the version now stored, so the
+// client can fetch it and merge its changes. A model without its primary
+// key set is refused rather than updating every row at the version.
+func UpdateVersioned(ctx context.Context, db *gorm.DB, model interface{}, expected int64, updates map[string]interface{}) error {
+	tx := Conn(ctx, db)
+	pk, id, err := primaryKey(tx, model)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(updates)+1)
+	for column, value := range updates {
+		values[column] = value
+	}
+	values[versionColumn] = gorm.Expr(versionColumn + " + 1")
+
+	result := tx.Model(model).
+		Clauses(clause.Returning{}).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: id}).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: expected}).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	current, err := currentVersion(tx, pk, id)
+	if err != nil {
+		return err
+	}
+	return errors.NewConflictError("resource was modified by another request").
+		WithMessageID("conflict.version", nil).
+		WithMeta("current_version", current)
+}
+
+// primaryKey returns the primary key field of model and its value, or an
+// error when it is not set
+func primaryKey(db *gorm.DB, model interface{}) (*schema.Field, interface{}, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse model: %v", err)
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, nil, fmt.Errorf("failed to parse model: %s has no primary key", stmt.Schema.Name)
+	}
+	id, zero := pk.ValueOf(db.Statement.Context, reflect.Indirect(reflect.ValueOf(model)))
+	if zero {
+		return nil, nil, fmt.Errorf("cannot update %s without its primary key", stmt.Schema.Name)
+	}
+	return pk, id, nil
+}
+
+// currentVersion returns the stored version of the record of model with
+// primary key id, or a not found error when it no longer exists
+func currentVersion(db *gorm.DB, pk *schema.Field, id interface{}) (int64, error) {
+	var versions []int64
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Model(reflect.New(pk.Schema.ModelType).Interface()).
+		Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: id}).
+		Limit(1).
+		Pluck(versionColumn, &versions).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, errors.NewNotFoundError("record not found")
+	}
+	return versions[0], nil
+}
@@ -0,0 +1,54 @@
+package environment
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Env is the deployment environment the service runs in
+type Env string
+
+// Canonical environments
+const (
+	Development Env = "development"
+	Test        Env = "test"
+	Staging     Env = "staging"
+	Production  Env = "production"
+)
+
+// aliases maps accepted spellings to their canonical environment
+var aliases = map[string]Env{
+	"development": Development,
+	"dev":         Development,
+	"local":       Development,
+	"test":        Test,
+	"testing":     Test,
+	"staging":     Staging,
+	"stage":       Staging,
+	"production":  Production,
+	"prod":        Production,
+}
+
+// Parse returns the canonical environment for s, accepting common
+// abbreviations such as "dev" and "prod" in any case
+func Parse(s string) (Env, error) {
+	if env, ok := aliases[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return env, nil
+	}
+	return "", fmt.Errorf("unknown environment %q", s)
+}
+
+// IsProduction reports whether e is the production environment
+func (e Env) IsProduction() bool {
+	return e == Production
+}
+
+// IsDevelopment reports whether e is the local development environment
+func (e Env) IsDevelopment() bool {
+	return e == Development
+}
+
+// String returns the environment name
+func (e Env) String() string {
+	return string(e)
+}
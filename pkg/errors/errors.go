@@ -28,6 +28,18 @@ type Error struct {
 	Message string    `json:"message"`
 	Status  int       `json:"status"`
 	Details []string  `json:"details,omitempty"`
+	// Meta carries structured data clients act on, such as the current
+	// version of a resource after a conflict
+	Meta map[string]interface{} `json:"meta,omitempty"`
+
+	// MessageID and Params identify the message in the translation catalog
+	MessageID string                 `json:"-"`
+	Params    map[string]interface{} `json:"-"`
+}
+
+// Translator translates catalog message IDs
+type Translator interface {
+	Translate(id string, params map[string]interface{}) (string, bool)
 }
 
 // Error implements the error interface
@@ -40,6 +52,39 @@ func (e *Error) HTTPStatusCode() int {
 	return e.Status
 }
 
+// WithMessageID attaches a catalog message ID and its parameters to the error
+func (e *Error) WithMessageID(id string, params map[string]interface{}) *Error {
+	e.MessageID = id
+	e.Params = params
+	return e
+}
+
+// WithMeta attaches a structured value to the error
+func (e *Error) WithMeta(key string, value interface{}) *Error {
+	if e.Meta == nil {
+		e.Meta = make(map[string]interface{})
+	}
+	e.Meta[key] = value
+	return e
+}
+
+// Localize returns a copy of the error with its message translated, or the
+// error itself when it has no message ID or no translation is available
+func (e *Error) Localize(t Translator) *Error {
+	if e.MessageID == "" || t == nil {
+		return e
+	}
+
+	message, ok := t.Translate(e.MessageID, e.Params)
+	if !ok {
+		return e
+	}
+
+	localized := *e
+	localized.Message = message
+	return &localized
+}
+
 // NewError creates a new Error
 func NewError(errorType ErrorType, message string, status int, details ...string) *Error {
 	return &Error{
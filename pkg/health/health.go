@@ -0,0 +1,230 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Status values reported for checks and for the service as a whole
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Defaults for checks registered without a timeout or cache duration
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 5 * time.Second
+)
+
+// Check is a health check of one component
+type Check struct {
+	Name string
+	// Func returns nil when the component is healthy
+	Func func(ctx context.Context) error
+	// Timeout bounds a single run of Func
+	Timeout time.Duration
+	// CacheTTL is how long a result is reused, so probes don't hammer the
+	// component; a negative value disables caching
+	CacheTTL time.Duration
+	// Liveness includes the check in /healthz. Only checks whose failure
+	// needs a restart belong there; dependencies such as the database are
+	// readiness checks.
+	Liveness bool
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the JSON body of the health endpoints
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type entry struct {
+	check Check
+
+	mu     sync.Mutex
+	result Result
+	expiry time.Time
+}
+
+// Registry holds the health checks of the service
+type Registry struct {
+	mu       sync.RWMutex
+	entries  map[string]*entry
+	draining atomic.Bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*entry)}
+}
+
+// Register adds a check, replacing any check with the same name
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultTimeout
+	}
+	if check.CacheTTL == 0 {
+		check.CacheTTL = DefaultCacheTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[check.Name] = &entry{check: check}
+}
+
+// SetDraining marks the service as shutting down, failing readiness so the
+// load balancer stops sending traffic while in-flight requests finish
+func (r *Registry) SetDraining(draining bool) {
+	r.draining.Store(draining)
+}
+
+// Liveness runs the liveness checks
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+// Readiness runs every check and fails while the service is draining
+func (r *Registry) Readiness(ctx context.Context) Report {
+	report := r.run(ctx, false)
+	if r.draining.Load() {
+		report.Status = StatusFail
+		report.Checks["draining"] = Result{
+			Status:    StatusFail,
+			Error:     "service is shutting down",
+			Duration:  "0s",
+			CheckedAt: time.Now(),
+		}
+	}
+	return report
+}
+
+// run executes the selected checks concurrently, reusing cached results
+func (r *Registry) run(ctx context.Context, livenessOnly bool) Report {
+	r.mu.RLock()
+	entries := make([]*entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if !livenessOnly || e.check.Liveness {
+			entries = append(entries, e)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].check.Name < entries[j].check.Name })
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = e.get(ctx)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(entries))}
+	for i, e := range entries {
+		report.Checks[e.check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// get returns the cached result or runs the check. Concurrent probes wait
+// for a single run instead of each running the check.
+func (e *entry) get(ctx context.Context) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if e.check.CacheTTL > 0 && now.Before(e.expiry) {
+		return e.result
+	}
+
+	// The result is shared with other probes, so a probe that goes away
+	// must not cancel the run
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.check.Timeout)
+	defer cancel()
+
+	err := runCheck(ctx, e.check.Func)
+	result := Result{
+		Status:    StatusOK,
+		Duration:  time.Since(now).String(),
+		CheckedAt: now,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		if e.result.Status != StatusFail {
+			logger.Warn("Health check failed", zap.String("check", e.check.Name), zap.Error(err))
+		}
+	}
+
+	e.result = result
+	e.expiry = now.Add(e.check.CacheTTL)
+	return result
+}
+
+// runCheck runs fn, giving up when ctx expires even if fn ignores it
+func runCheck(ctx context.Context, fn func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out: %v", ctx.Err())
+	}
+}
+
+// Register mounts the health endpoints on the router
+//
+//	GET /healthz  liveness: the process is up and not wedged
+//	GET /readyz   readiness: the service can take traffic
+//
+// Both answer 200 when every check passes and 503 otherwise, with a Report
+// as the body.
+func Register(router gin.IRouter, registry *Registry) {
+	router.GET("/healthz", func(c *gin.Context) {
+		respond(c, registry.Liveness(c.Request.Context()))
+	})
+	router.GET("/readyz", func(c *gin.Context) {
+		respond(c, registry.Readiness(c.Request.Context()))
+	})
+}
+
+func respond(c *gin.Context, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,303 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var embeddedLocales embed.FS
+
+// DefaultLocale is the last locale of every fallback chain
+const DefaultLocale = "en"
+
+// CountParam is the parameter used to select a plural form
+const CountParam = "count"
+
+// Ref is a parameter value that is itself a catalog message, such as a
+// field name in a validation message. It is translated in the locale of the
+// enclosing message and falls back to Default.
+type Ref struct {
+	ID      string
+	Default string
+}
+
+// String returns the untranslated default
+func (r Ref) String() string {
+	return r.Default
+}
+
+// message holds the plural forms of a catalog entry; messages without
+// plural variants only have an "other" form
+type message map[string]string
+
+// UnmarshalJSON accepts either a plain string or an object of plural forms
+func (m *message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = message{"other": s}
+		return nil
+	}
+
+	var forms map[string]string
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms["other"]; !ok {
+		return fmt.Errorf("plural message is missing the \"other\" form")
+	}
+	*m = forms
+	return nil
+}
+
+// Catalog holds translated messages keyed by locale and message ID
+type Catalog struct {
+	defaultLocale string
+	messages      map[string]map[string]message
+}
+
+// NewCatalog creates a catalog from the locale files embedded in the binary
+func NewCatalog() (*Catalog, error) {
+	sub, err := fs.Sub(embeddedLocales, "locales")
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalogFS(sub, DefaultLocale)
+}
+
+// NewCatalogFS creates a catalog from the <locale>.json files found in fsys
+func NewCatalogFS(fsys fs.FS, defaultLocale string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		defaultLocale: Canonicalize(defaultLocale),
+		messages:      make(map[string]map[string]message, len(files)),
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]message
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse locale file %q: %v", file, err)
+		}
+		c.messages[Canonicalize(strings.TrimSuffix(path.Base(file), ".json"))] = messages
+	}
+
+	if _, ok := c.messages[c.defaultLocale]; !ok {
+		return nil, fmt.Errorf("default locale %q has no messages", c.defaultLocale)
+	}
+	return c, nil
+}
+
+// Locales returns the locales available in the catalog
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supports reports whether the catalog has messages for locale or its base language
+func (c *Catalog) Supports(locale string) bool {
+	for _, l := range fallbackChain(Canonicalize(locale)) {
+		if _, ok := c.messages[l]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Localizer returns a localizer for the first supported locale in the
+// given order of preference, falling back to the default locale
+func (c *Catalog) Localizer(locales ...string) *Localizer {
+	var chain []string
+	seen := map[string]bool{}
+	add := func(l string) {
+		if _, ok := c.messages[l]; ok && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+
+	for _, locale := range locales {
+		for _, l := range fallbackChain(Canonicalize(locale)) {
+			add(l)
+		}
+	}
+	add(c.defaultLocale)
+
+	return &Localizer{catalog: c, chain: chain}
+}
+
+// Localizer translates messages for a resolved locale fallback chain
+type Localizer struct {
+	catalog *Catalog
+	chain   []string
+}
+
+// Locale returns the preferred locale of the localizer
+func (l *Localizer) Locale() string {
+	return l.chain[0]
+}
+
+// Translate looks up the message ID along the fallback chain and
+// substitutes {name} placeholders with params. The plural form is chosen
+// from params["count"] when present.
+func (l *Localizer) Translate(id string, params map[string]interface{}) (string, bool) {
+	for _, locale := range l.chain {
+		msg, ok := l.catalog.messages[locale][id]
+		if !ok {
+			continue
+		}
+
+		form := msg["other"]
+		if count, ok := params[CountParam]; ok {
+			if f, ok := msg[pluralCategory(locale, toInt(count))]; ok {
+				form = f
+			}
+		}
+		return interpolate(form, l.resolveRefs(params)), true
+	}
+	return "", false
+}
+
+// resolveRefs returns a copy of params with Ref values translated, or
+// params itself when there are none
+func (l *Localizer) resolveRefs(params map[string]interface{}) map[string]interface{} {
+	var resolved map[string]interface{}
+	for k, v := range params {
+		ref, ok := v.(Ref)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = make(map[string]interface{}, len(params))
+			for k, v := range params {
+				resolved[k] = v
+			}
+		}
+		if text, ok := l.Translate(ref.ID, nil); ok {
+			resolved[k] = text
+		}
+	}
+	if resolved == nil {
+		return params
+	}
+	return resolved
+}
+
+// T translates the message ID with key/value parameter pairs, returning the
+// ID itself when no translation exists
+func (l *Localizer) T(id string, keyValues ...interface{}) string {
+	params := make(map[string]interface{}, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		params[fmt.Sprint(keyValues[i])] = keyValues[i+1]
+	}
+
+	if s, ok := l.Translate(id, params); ok {
+		return s
+	}
+	return id
+}
+
+// Canonicalize normalizes a language tag such as "en_us" to "en-US"
+func Canonicalize(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	if len(parts) == 0 || parts[0] == "" {
+		return ""
+	}
+
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else {
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// fallbackChain returns locale followed by its progressively shorter prefixes,
+// e.g. "hi-Latn-IN" -> ["hi-Latn-IN", "hi-Latn", "hi"]
+func fallbackChain(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+
+	var chain []string
+	for {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			return chain
+		}
+		locale = locale[:i]
+	}
+}
+
+func interpolate(s string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	default:
+		return 0
+	}
+}
+
+type contextKey struct{}
+
+// WithLocales returns a copy of ctx carrying the preferred locales in
+// descending order of preference
+func WithLocales(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locales)
+}
+
+// LocalesFromContext returns the preferred locales stored in ctx, if any
+func LocalesFromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(contextKey{}).([]string)
+	return locales
+}
+
+// FromContext returns a localizer for the locales stored in ctx
+func (c *Catalog) FromContext(ctx context.Context) *Localizer {
+	return c.Localizer(LocalesFromContext(ctx)...)
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{"en", 0, PluralOther},
+		{"en", 1, PluralOne},
+		{"en", 2, PluralOther},
+		{"en-IN", 1, PluralOne},
+		{"hi", 0, PluralOne},
+		{"hi", 1, PluralOne},
+		{"hi", 2, PluralOther},
+		{"hi-IN", 0, PluralOne},
+		{"fr", 0, PluralOne},
+		{"ja", 1, PluralOther},
+		{"xx", 1, PluralOne},
+		{"xx", 5, PluralOther},
+	}
+	for _, tt := range tests {
+		if got := pluralCategory(tt.locale, tt.n); got != tt.want {
+			t.Errorf("pluralCategory(%q, %d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	catalog, err := NewCatalogFS(fstest.MapFS{
+		"en.json": {Data: []byte(`{
+			"greeting": "Hello {name}",
+			"items": {"one": "{count} item", "other": "{count} items"},
+			"only_en": "English"
+		}`)},
+		"hi.json": {Data: []byte(`{
+			"items": {"one": "{count} वस्तु", "other": "{count} वस्तुएँ"}
+		}`)},
+	}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		locales []string
+		id      string
+		params  []interface{}
+		want    string
+	}{
+		{[]string{"en"}, "greeting", []interface{}{"name", "Asha"}, "Hello Asha"},
+		{[]string{"en"}, "items", []interface{}{"count", 1}, "1 item"},
+		{[]string{"en"}, "items", []interface{}{"count", 0}, "0 items"},
+		{[]string{"hi-IN"}, "items", []interface{}{"count", 0}, "0 वस्तु"},
+		{[]string{"hi"}, "items", []interface{}{"count", "3"}, "3 वस्तुएँ"},
+		{[]string{"hi"}, "only_en", nil, "English"},
+		{[]string{"fr"}, "greeting", []interface{}{"name", "Léa"}, "Hello Léa"},
+		{[]string{"en"}, "missing", nil, "missing"},
+	}
+	for _, tt := range tests {
+		if got := catalog.Localizer(tt.locales...).T(tt.id, tt.params...); got != tt.want {
+			t.Errorf("T(%v, %q) = %q, want %q", tt.locales, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestNewCatalogFSRejectsPluralWithoutOther(t *testing.T) {
+	_, err := NewCatalogFS(fstest.MapFS{
+		"en.json": {Data: []byte(`{"items": {"one": "{count} item"}}`)},
+	}, "en")
+	if err == nil {
+		t.Fatal("NewCatalogFS() accepted a plural message without an other form")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := map[string]string{
+		"en_us":      "en-US",
+		" HI-in ":    "hi-IN",
+		"hi-latn-in": "hi-latn-IN",
+		"":           "",
+	}
+	for in, want := range tests {
+		if got := Canonicalize(in); got != want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"hi-IN,hi;q=0.9,en;q=0.8", []string{"hi-IN", "hi", "en"}},
+		{"en;q=0.5, hi", []string{"hi", "en"}},
+		{"*, fr;q=0, de", []string{"de"}},
+	}
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
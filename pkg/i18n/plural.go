@@ -0,0 +1,63 @@
+package i18n
+
+import "strings"
+
+// Plural categories as defined by CLDR
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// pluralRules maps a base language to its CLDR cardinal rule for integers
+var pluralRules = map[string]func(n int) string{
+	"en": oneIfOne,
+	"de": oneIfOne,
+	"es": oneIfOne,
+	"it": oneIfOne,
+	"nl": oneIfOne,
+	"ta": oneIfOne,
+	"te": oneIfOne,
+	"ml": oneIfOne,
+	"kn": oneIfOne,
+	"mr": oneIfOne,
+	"hi": oneIfZeroOrOne,
+	"bn": oneIfZeroOrOne,
+	"gu": oneIfZeroOrOne,
+	"fr": oneIfZeroOrOne,
+	"ja": alwaysOther,
+	"zh": alwaysOther,
+}
+
+func pluralCategory(locale string, n int) string {
+	lang := locale
+	if i := strings.Index(locale, "-"); i >= 0 {
+		lang = locale[:i]
+	}
+
+	if rule, ok := pluralRules[lang]; ok {
+		return rule(n)
+	}
+	return oneIfOne(n)
+}
+
+func oneIfOne(n int) string {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func oneIfZeroOrOne(n int) string {
+	if n == 0 || n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func alwaysOther(int) string {
+	return PluralOther
+}
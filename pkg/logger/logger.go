@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"time"
 
+	"github.com/hacKRD0/trikona_go/pkg/environment"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -11,10 +13,14 @@ import (
 var (
 	// Log is the global logger instance
 	Log *zap.Logger
+
+	// atomicLevel is the level of Log, which SetLevel changes at runtime
+	atomicLevel = zap.NewAtomicLevel()
 )
 
-// InitLogger initializes the global logger
-func InitLogger() error {
+// InitLogger initializes the global logger at the given level
+// (debug, info, warn or error)
+func InitLogger(level string) error {
 	// Configure encoder
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
@@ -31,19 +37,16 @@ func InitLogger() error {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	// Set log level based on environment
-	var level zapcore.Level
-	if os.Getenv("ENV") == "production" {
-		level = zapcore.InfoLevel
-	} else {
-		level = zapcore.DebugLevel
+	// Parse log level
+	if err := SetLevel(level); err != nil {
+		return err
 	}
 
 	// Create core
 	core := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		zapcore.AddSync(os.Stdout),
-		level,
+		atomicLevel,
 	)
 
 	// Create logger
@@ -52,6 +55,25 @@ func InitLogger() error {
 	return nil
 }
 
+// DefaultLevel returns the log level used when none is configured: info in
+// production and debug elsewhere
+func DefaultLevel(env environment.Env) string {
+	if env.IsProduction() {
+		return "info"
+	}
+	return "debug"
+}
+
+// SetLevel changes the level of the global logger at runtime
+func SetLevel(level string) error {
+	zapLevel, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(zapLevel)
+	return nil
+}
+
 // Info logs an info message with fields
 func Info(msg string, fields ...zap.Field) {
 	Log.Info(msg, fields...)
@@ -84,6 +106,29 @@ func WithRequestID(requestID string) *zap.Logger {
 	return Log.With(zap.String("request_id", requestID))
 }
 
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the request ID, so code
+// without access to the HTTP request can correlate its logs
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the global logger, with the request ID of ctx when
+// it carries one
+func FromContext(ctx context.Context) *zap.Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return WithRequestID(requestID)
+	}
+	return Log
+}
+
 // WithDuration creates a logger with duration
 func WithDuration(start time.Time) zap.Field {
 	return zap.Duration("duration", time.Since(start))
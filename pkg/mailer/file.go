@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type fileMailer struct {
+	dir  string
+	from Address
+}
+
+// NewFileMailer creates a mailer that writes every message as an .eml file
+// into dir instead of delivering it
+func NewFileMailer(dir string, from Address) (Mailer, error) {
+	if dir == "" {
+		return nil, errors.New("mailer file directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mailer file directory: %v", err)
+	}
+
+	return &fileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes the message to a new .eml file
+func (m *fileMailer) Send(ctx context.Context, msg *Message) error {
+	msg, err := withDefaults(msg, m.from)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	raw, err := BuildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.dir, name), raw, 0o644)
+}
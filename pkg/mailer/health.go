@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"net"
+)
+
+// Pinger is implemented by transports that can check that their provider
+// is reachable without sending a message
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck returns a health check for the mailer. Transports that do not
+// implement Pinger, such as the Mailjet API client, are reported healthy;
+// provider outages there surface as retried outbox deliveries instead.
+func HealthCheck(m Mailer) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if p, ok := m.(Pinger); ok {
+			return p.Ping(ctx)
+		}
+		return nil
+	}
+}
+
+// Ping checks that the SMTP relay accepts connections
+func (m *smtpMailer) Ping(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
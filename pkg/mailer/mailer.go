@@ -0,0 +1,95 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hacKRD0/trikona_go/pkg/config"
+)
+
+// Transport names accepted by config.MailConfig.Transport
+const (
+	TransportMailjet = "mailjet"
+	TransportSMTP    = "smtp"
+	TransportFile    = "file"
+	TransportMemory  = "memory"
+)
+
+// Message categories. Transactional emails (verification, password reset,
+// invitations) are always sent; recipients may opt out of the others.
+const (
+	CategoryTransactional = "transactional"
+	CategorySecurity      = "security"
+	CategoryProduct       = "product"
+	CategoryMarketing     = "marketing"
+)
+
+// Address represents an email address with an optional display name
+type Address struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Message represents an outgoing email
+type Message struct {
+	From     Address           `json:"from"`
+	To       []Address         `json:"to"`
+	Subject  string            `json:"subject"`
+	Text     string            `json:"text,omitempty"`
+	HTML     string            `json:"html,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Locale   string            `json:"locale,omitempty"`
+	Category string            `json:"category,omitempty"`
+	// IdempotencyKey identifies a logical send so that retries and
+	// duplicate requests do not deliver the same email twice
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// Optional reports whether recipients may opt out of the message category
+func (m *Message) Optional() bool {
+	return m.Category != "" && m.Category != CategoryTransactional
+}
+
+// Mailer defines the interface for delivering emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New creates the mailer selected by the configured transport
+func New(cfg *config.MailConfig) (Mailer, error) {
+	from := Address{Email: cfg.FromEmail, Name: cfg.FromName}
+
+	switch cfg.Transport {
+	case TransportMailjet:
+		return NewMailjetMailer(cfg.MailjetAPIKey, cfg.MailjetSecretKey, from)
+	case TransportSMTP:
+		return NewSMTPMailer(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort), cfg.SMTPUsername, cfg.SMTPPassword, from)
+	case TransportFile:
+		return NewFileMailer(cfg.FileDir, from)
+	case TransportMemory:
+		return NewMemoryMailer(from), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer transport %q", cfg.Transport)
+	}
+}
+
+// withDefaults returns a copy of msg with the default sender applied
+func withDefaults(msg *Message, from Address) (*Message, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("message is nil")
+	}
+	if len(msg.To) == 0 {
+		return nil, fmt.Errorf("message has no recipients")
+	}
+
+	out := *msg
+	if out.From.Email == "" {
+		out.From = from
+	}
+	if out.From.Email == "" {
+		return nil, fmt.Errorf("message has no sender")
+	}
+	return &out, nil
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mailjet/mailjet-apiv3-go"
+)
+
+// mailjetTimeout bounds a send API call whose context has no deadline, so a
+// hung connection cannot block the caller indefinitely
+const mailjetTimeout = time.Minute
+
+type mailjetMailer struct {
+	http *mailjet.HTTPClient
+	from Address
+}
+
+// NewMailjetMailer creates a mailer that delivers through the Mailjet send API
+func NewMailjetMailer(apiKey, secretKey string, from Address) (Mailer, error) {
+	if apiKey == "" || secretKey == "" {
+		return nil, errors.New("mailjet API key and secret key are required")
+	}
+
+	httpClient := mailjet.NewHTTPClient(apiKey, secretKey)
+	httpClient.SetClient(&http.Client{Timeout: mailjetTimeout})
+
+	return &mailjetMailer{
+		http: httpClient,
+		from: from,
+	}, nil
+}
+
+// Send delivers the message through Mailjet
+func (m *mailjetMailer) Send(ctx context.Context, msg *Message) error {
+	msg, err := withDefaults(msg, m.from)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	to := make(mailjet.RecipientsV31, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, mailjet.RecipientV31{Email: addr.Email, Name: addr.Name})
+	}
+
+	info := mailjet.InfoMessagesV31{
+		From: &mailjet.RecipientV31{
+			Email: msg.From.Email,
+			Name:  msg.From.Name,
+		},
+		To:       &to,
+		Subject:  msg.Subject,
+		TextPart: msg.Text,
+		HTMLPart: msg.HTML,
+		CustomID: msg.IdempotencyKey,
+	}
+	if len(msg.Headers) > 0 {
+		info.Headers = make(map[string]interface{}, len(msg.Headers))
+		for k, v := range msg.Headers {
+			info.Headers[k] = v
+		}
+	}
+
+	client := mailjet.NewClient(contextHTTPClient{HTTPClientInterface: m.http, ctx: ctx}, nil)
+	_, err = client.SendMailV31(&mailjet.MessagesV31{Info: []mailjet.InfoMessagesV31{info}})
+	return err
+}
+
+// contextHTTPClient sends the Mailjet send API request under ctx, which the
+// Mailjet client does not accept itself, so that a cancelled or expired
+// context aborts the call
+type contextHTTPClient struct {
+	mailjet.HTTPClientInterface
+	ctx context.Context
+}
+
+// SendMailV31 sends req under the client's context
+func (c contextHTTPClient) SendMailV31(req *http.Request) (*http.Response, error) {
+	return c.HTTPClientInterface.SendMailV31(req.WithContext(c.ctx))
+}
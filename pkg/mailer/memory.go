@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMemoryCapacity is the number of messages kept by a memory mailer
+const DefaultMemoryCapacity = 500
+
+// CapturedMessage is a message recorded by the memory mailer
+type CapturedMessage struct {
+	ID         string    `json:"id"`
+	ReceivedAt time.Time `json:"received_at"`
+	Message    *Message  `json:"message"`
+	Raw        []byte    `json:"-"`
+}
+
+// MemoryMailer captures messages in memory instead of delivering them.
+// Only the most recent DefaultMemoryCapacity messages are kept.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	from     Address
+	capacity int
+	messages []*CapturedMessage
+}
+
+// NewMemoryMailer creates a new in-memory mailer
+func NewMemoryMailer(from Address) *MemoryMailer {
+	return &MemoryMailer{
+		from:     from,
+		capacity: DefaultMemoryCapacity,
+	}
+}
+
+// Send records the message
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	msg, err := withDefaults(msg, m.from)
+	if err != nil {
+		return err
+	}
+
+	raw, err := BuildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, &CapturedMessage{
+		ID:         uuid.New().String(),
+		ReceivedAt: time.Now(),
+		Message:    msg,
+		Raw:        raw,
+	})
+	if len(m.messages) > m.capacity {
+		m.messages = m.messages[len(m.messages)-m.capacity:]
+	}
+	return nil
+}
+
+// Messages returns a snapshot of the captured messages in send order
+func (m *MemoryMailer) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]*Message, len(m.messages))
+	for i, captured := range m.messages {
+		out[i] = captured.Message
+	}
+	return out
+}
+
+// Captured returns a snapshot of the captured messages, newest first
+func (m *MemoryMailer) Captured() []*CapturedMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]*CapturedMessage, len(m.messages))
+	for i, captured := range m.messages {
+		out[len(m.messages)-1-i] = captured
+	}
+	return out
+}
+
+// Get returns the captured message with the given ID
+func (m *MemoryMailer) Get(id string) (*CapturedMessage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, captured := range m.messages {
+		if captured.ID == id {
+			return captured, true
+		}
+	}
+	return nil, false
+}
+
+// Reset discards all captured messages
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSuppressed is returned when a message is not sent because every
+// recipient is suppressed or opted out; it must not be retried
+var ErrSuppressed = errors.New("all recipients are suppressed")
+
+// MailerFunc adapts a function to the Mailer interface
+type MailerFunc func(ctx context.Context, msg *Message) error
+
+// Send calls f(ctx, msg)
+func (f MailerFunc) Send(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// Middleware wraps a mailer with additional behaviour such as filtering
+type Middleware func(next Mailer) Mailer
+
+// Chain wraps m with the middlewares; the first middleware sees the
+// message first
+func Chain(m Mailer, middlewares ...Middleware) Mailer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		m = middlewares[i](m)
+	}
+	return m
+}
@@ -0,0 +1,130 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BuildMIME renders the message as an RFC 5322 document with a
+// multipart/alternative body when both text and HTML parts are present
+func BuildMIME(msg *Message) ([]byte, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("message is nil")
+	}
+
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", formatAddress(msg.From))
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, formatAddress(addr))
+	}
+	header.Set("To", strings.Join(to, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", newMessageID(msg.From.Email))
+	header.Set("MIME-Version", "1.0")
+	if msg.Locale != "" {
+		header.Set("Content-Language", msg.Locale)
+	}
+	for k, v := range msg.Headers {
+		header.Set(k, v)
+	}
+
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		mw := multipart.NewWriter(&buf)
+		header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+		writeHeader(&buf, header)
+
+		if err := writePart(mw, "text/plain; charset=utf-8", msg.Text); err != nil {
+			return nil, err
+		}
+		if err := writePart(mw, "text/html; charset=utf-8", msg.HTML); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		body, contentType := msg.Text, "text/plain; charset=utf-8"
+		if msg.HTML != "" {
+			body, contentType = msg.HTML, "text/html; charset=utf-8"
+		}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", stripNewlines(k), stripNewlines(v))
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+// stripNewlines removes CR and LF from a header field, so that values such
+// as names, addresses or custom headers cannot inject further headers
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func formatAddress(addr Address) string {
+	return (&mail.Address{Name: addr.Name, Address: addr.Email}).String()
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
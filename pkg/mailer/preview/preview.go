@@ -0,0 +1,174 @@
+package preview
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+)
+
+//go:embed ui/*.html.tmpl
+var ui embed.FS
+
+var (
+	indexTemplate   = template.Must(template.ParseFS(ui, "ui/index.html.tmpl"))
+	messageTemplate = template.Must(template.ParseFS(ui, "ui/message.html.tmpl"))
+)
+
+// summary is the JSON representation of a captured message in listings
+type summary struct {
+	ID         string           `json:"id"`
+	ReceivedAt string           `json:"received_at"`
+	From       mailer.Address   `json:"from"`
+	To         []mailer.Address `json:"to"`
+	Subject    string           `json:"subject"`
+	Template   string           `json:"template,omitempty"`
+	Locale     string           `json:"locale,omitempty"`
+	Category   string           `json:"category,omitempty"`
+}
+
+// Register mounts the mail preview UI and JSON API on the router group.
+// It is meant for local development only and must not be exposed in
+// production since it shows verification and reset tokens.
+//
+//	GET    /                   HTML list of captured messages
+//	GET    /messages/:id       HTML detail view
+//	GET    /messages/:id/html  HTML part
+//	GET    /messages/:id/text  text part
+//	GET    /messages/:id/raw   raw MIME document
+//	POST   /clear              discard all messages (UI)
+//	GET    /api/messages       JSON list
+//	GET    /api/messages/:id   JSON message
+//	DELETE /api/messages       discard all messages
+func Register(r gin.IRouter, m *mailer.MemoryMailer) {
+	h := &handler{mailer: m}
+
+	r.GET("/", h.index)
+	r.POST("/clear", h.clear)
+	r.GET("/messages/:id", h.message)
+	r.GET("/messages/:id/html", h.html)
+	r.GET("/messages/:id/text", h.text)
+	r.GET("/messages/:id/raw", h.raw)
+
+	api := r.Group("/api")
+	api.GET("/messages", h.list)
+	api.GET("/messages/:id", h.get)
+	api.DELETE("/messages", h.reset)
+}
+
+// NewServer creates a standalone HTTP server serving the preview UI on addr
+func NewServer(addr string, m *mailer.MemoryMailer) *http.Server {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	Register(engine, m)
+
+	return &http.Server{
+		Addr:    addr,
+		Handler: engine,
+	}
+}
+
+type handler struct {
+	mailer *mailer.MemoryMailer
+}
+
+func (h *handler) index(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(c.Writer, h.mailer.Captured()); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+func (h *handler) message(c *gin.Context) {
+	captured, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := messageTemplate.Execute(c.Writer, captured); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+func (h *handler) html(c *gin.Context) {
+	captured, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	// Email HTML is untrusted; forbid scripts when it is opened directly
+	c.Header("Content-Security-Policy", "script-src 'none'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(captured.Message.HTML))
+}
+
+func (h *handler) text(c *gin.Context) {
+	captured, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(captured.Message.Text))
+}
+
+func (h *handler) raw(c *gin.Context) {
+	captured, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	// Served as text so that browsers display rather than download it
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", captured.Raw)
+}
+
+func (h *handler) clear(c *gin.Context) {
+	h.mailer.Reset()
+	c.Redirect(http.StatusSeeOther, "./")
+}
+
+func (h *handler) list(c *gin.Context) {
+	captured := h.mailer.Captured()
+	out := make([]summary, 0, len(captured))
+	for _, m := range captured {
+		out = append(out, summary{
+			ID:         m.ID,
+			ReceivedAt: m.ReceivedAt.Format("2006-01-02T15:04:05.000Z07:00"),
+			From:       m.Message.From,
+			To:         m.Message.To,
+			Subject:    m.Message.Subject,
+			Template:   m.Message.Template,
+			Locale:     m.Message.Locale,
+			Category:   m.Message.Category,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": out})
+}
+
+func (h *handler) get(c *gin.Context) {
+	captured, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          captured.ID,
+		"received_at": captured.ReceivedAt,
+		"message":     captured.Message,
+		"raw":         string(captured.Raw),
+	})
+}
+
+func (h *handler) reset(c *gin.Context) {
+	h.mailer.Reset()
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) lookup(c *gin.Context) (*mailer.CapturedMessage, bool) {
+	captured, ok := h.mailer.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return nil, false
+	}
+	return captured, true
+}
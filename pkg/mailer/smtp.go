@@ -0,0 +1,134 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// smtpTimeout bounds a delivery whose context has no deadline, so a hung
+// relay cannot block the caller indefinitely
+const smtpTimeout = time.Minute
+
+type smtpMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from Address
+}
+
+// NewSMTPMailer creates a mailer that delivers through an SMTP relay
+func NewSMTPMailer(host, port, username, password string, from Address) (Mailer, error) {
+	if host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{
+		host: host,
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}, nil
+}
+
+// Send delivers the message to the SMTP relay
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	msg, err := withDefaults(msg, m.from)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	raw, err := BuildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, addr.Email)
+	}
+
+	return m.send(ctx, msg.From.Email, to, raw)
+}
+
+// send runs the SMTP conversation like smtp.SendMail, but dials with ctx
+// and aborts the connection when ctx is done or its deadline passes
+func (m *smtpMailer) send(ctx context.Context, from string, to []string, raw []byte) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("SMTP server does not support AUTH")
+		}
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	// The relay has accepted the message; a failed QUIT must not make the
+	// caller send it again
+	client.Quit()
+	return nil
+}
@@ -0,0 +1,213 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// Template names shipped with the service
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateWelcome       = "welcome"
+	TemplateInvite        = "invite"
+	TemplateSecurityAlert = "security_alert"
+)
+
+// Button is the data passed to the shared "button" partial
+type Button struct {
+	URL   string
+	Label string
+	Color string
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// Registry holds the parsed email templates keyed by name
+//
+// Each template is made of up to three files in the templates directory:
+// <name>.subject.tmpl (required), <name>.html.tmpl and <name>.txt.tmpl, at
+// least one of which must exist. Body files define a "content" block (and
+// a "title" block for HTML) that is rendered inside the shared layout.
+// Templates translate their copy with {{t "message.id" "param" value}} and
+// can read the active locale with {{locale}}.
+type Registry struct {
+	catalog   *i18n.Catalog
+	templates map[string]*emailTemplate
+}
+
+// NewRegistry creates a registry from the templates embedded in the binary
+func NewRegistry(catalog *i18n.Catalog) (*Registry, error) {
+	sub, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistryFS(sub, catalog)
+}
+
+// NewRegistryFS creates a registry from the templates found in fsys
+func NewRegistryFS(fsys fs.FS, catalog *i18n.Catalog) (*Registry, error) {
+	if catalog == nil {
+		return nil, fmt.Errorf("message catalog is required")
+	}
+
+	subjects, err := fs.Glob(fsys, "*.subject.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		catalog:   catalog,
+		templates: make(map[string]*emailTemplate, len(subjects)),
+	}
+	for _, file := range subjects {
+		name := strings.TrimSuffix(path.Base(file), ".subject.tmpl")
+		tmpl, err := parseEmailTemplate(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %q: %v", name, err)
+		}
+		r.templates[name] = tmpl
+	}
+
+	return r, nil
+}
+
+func parseEmailTemplate(fsys fs.FS, name string) (*emailTemplate, error) {
+	subject, err := texttemplate.New(name+".subject.tmpl").Funcs(textFuncs(nil)).ParseFS(fsys, name+".subject.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &emailTemplate{subject: subject}
+
+	if exists(fsys, name+".html.tmpl") {
+		patterns := append([]string{"layout.html.tmpl"}, partials(fsys, "*.html.tmpl")...)
+		patterns = append(patterns, name+".html.tmpl")
+		tmpl.html, err = htmltemplate.New(name+".html").Funcs(htmlFuncs(nil)).ParseFS(fsys, patterns...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if exists(fsys, name+".txt.tmpl") {
+		patterns := append([]string{"layout.txt.tmpl"}, partials(fsys, "*.txt.tmpl")...)
+		patterns = append(patterns, name+".txt.tmpl")
+		tmpl.text, err = texttemplate.New(name+".txt").Funcs(textFuncs(nil)).ParseFS(fsys, patterns...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tmpl.html == nil && tmpl.text == nil {
+		return nil, fmt.Errorf("no html or text body found")
+	}
+	return tmpl, nil
+}
+
+// Names returns the names of all registered templates
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the named template in the best matching locale and
+// returns a message with the subject, HTML and text parts filled in
+func (r *Registry) Render(name string, locales []string, data interface{}) (*Message, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	loc := r.catalog.Localizer(locales...)
+
+	subjectTmpl, err := tmpl.subject.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var subject bytes.Buffer
+	if err := subjectTmpl.Funcs(textFuncs(loc)).Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %q: %v", name, err)
+	}
+	msg := &Message{
+		Template: name,
+		Subject:  strings.TrimSpace(subject.String()),
+		Locale:   loc.Locale(),
+	}
+
+	if tmpl.html != nil {
+		htmlTmpl, err := tmpl.html.Clone()
+		if err != nil {
+			return nil, err
+		}
+		var html bytes.Buffer
+		if err := htmlTmpl.Funcs(htmlFuncs(loc)).ExecuteTemplate(&html, "layout", data); err != nil {
+			return nil, fmt.Errorf("failed to render html body of %q: %v", name, err)
+		}
+		msg.HTML = html.String()
+	}
+
+	if tmpl.text != nil {
+		textTmpl, err := tmpl.text.Clone()
+		if err != nil {
+			return nil, err
+		}
+		var text bytes.Buffer
+		if err := textTmpl.Funcs(textFuncs(loc)).ExecuteTemplate(&text, "layout", data); err != nil {
+			return nil, fmt.Errorf("failed to render text body of %q: %v", name, err)
+		}
+		msg.Text = text.String()
+	}
+
+	return msg, nil
+}
+
+// textFuncs returns the template functions bound to loc; a nil localizer
+// yields placeholders used only while parsing
+func textFuncs(loc *i18n.Localizer) texttemplate.FuncMap {
+	funcs := texttemplate.FuncMap{
+		"t":      func(id string, keyValues ...interface{}) string { return id },
+		"locale": func() string { return i18n.DefaultLocale },
+		"button": newButton,
+	}
+	if loc != nil {
+		funcs["t"] = loc.T
+		funcs["locale"] = loc.Locale
+	}
+	return funcs
+}
+
+func htmlFuncs(loc *i18n.Localizer) htmltemplate.FuncMap {
+	return htmltemplate.FuncMap(textFuncs(loc))
+}
+
+func newButton(url, label, color string) Button {
+	return Button{URL: url, Label: label, Color: color}
+}
+
+func partials(fsys fs.FS, pattern string) []string {
+	matches, _ := fs.Glob(fsys, path.Join("partials", pattern))
+	return matches
+}
+
+func exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
@@ -0,0 +1,164 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+// ErrThrottled is returned when a send exceeds a throttle rule. Callers of
+// enumeration-sensitive flows such as password reset should still respond
+// with success.
+var ErrThrottled = errors.New("email send rate limit exceeded")
+
+// ThrottledTotal counts throttled sends keyed by "<template>:<scope>" and is
+// published on /debug/vars
+var ThrottledTotal = expvar.NewMap("mailer_throttled_total")
+
+// ThrottleRule limits sends of a template ("" matches every template) per
+// recipient address and per requester (client IP or user ID)
+type ThrottleRule struct {
+	Template     string
+	PerRecipient ratelimit.Limit
+	PerRequester ratelimit.Limit
+}
+
+// DefaultThrottleRules returns the default throttle rules
+func DefaultThrottleRules() []ThrottleRule {
+	return []ThrottleRule{
+		{
+			Template:     TemplatePasswordReset,
+			PerRecipient: ratelimit.Limit{Count: 3, Window: time.Hour},
+			PerRequester: ratelimit.Limit{Count: 10, Window: time.Hour},
+		},
+		{
+			Template:     TemplateVerifyEmail,
+			PerRecipient: ratelimit.Limit{Count: 5, Window: time.Hour},
+			PerRequester: ratelimit.Limit{Count: 10, Window: time.Hour},
+		},
+		{
+			PerRecipient: ratelimit.Limit{Count: 20, Window: time.Hour},
+			PerRequester: ratelimit.Limit{Count: 50, Window: time.Hour},
+		},
+	}
+}
+
+// Throttler rejects sends that exceed per-recipient or per-requester rules
+type Throttler struct {
+	limiter *ratelimit.Limiter
+	mu      sync.RWMutex
+	rules   []ThrottleRule
+}
+
+// NewThrottler creates a new throttler
+func NewThrottler(rules []ThrottleRule) *Throttler {
+	return &Throttler{
+		limiter: ratelimit.NewLimiter(),
+		rules:   rules,
+	}
+}
+
+// SetRules replaces the throttle rules
+func (t *Throttler) SetRules(rules []ThrottleRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = rules
+}
+
+// Middleware returns a mailer middleware enforcing the throttle rules. It
+// belongs on the request path, where the requester is known, in front of
+// the outbox.
+func (t *Throttler) Middleware() Middleware {
+	return func(next Mailer) Mailer {
+		return MailerFunc(func(ctx context.Context, msg *Message) error {
+			if scope, ok := t.allow(ctx, msg); !ok {
+				template := msg.Template
+				if template == "" {
+					template = "none"
+				}
+				ThrottledTotal.Add(template+":"+scope, 1)
+				logger.Warn("Email send throttled",
+					zap.String("template", msg.Template),
+					zap.String("scope", scope),
+				)
+				return ErrThrottled
+			}
+			return next.Send(ctx, msg)
+		})
+	}
+}
+
+// allow checks every matching rule and records the send only when all of
+// them pass, so a rejected send does not use up the recipient's quota
+func (t *Throttler) allow(ctx context.Context, msg *Message) (string, bool) {
+	t.mu.RLock()
+	rules := t.rules
+	t.mu.RUnlock()
+
+	requester := RequesterFromContext(ctx)
+	var checks []ratelimit.Check
+	var scopes []string
+	for _, rule := range rules {
+		if rule.Template != "" && rule.Template != msg.Template {
+			continue
+		}
+
+		name := rule.Template
+		if name == "" {
+			name = "*"
+		}
+		for _, addr := range msg.To {
+			checks = append(checks, ratelimit.Check{
+				Key:   "recipient:" + name + ":" + strings.ToLower(addr.Email),
+				Limit: rule.PerRecipient,
+			})
+			scopes = append(scopes, "recipient")
+		}
+		if requester != "" {
+			checks = append(checks, ratelimit.Check{
+				Key:   "requester:" + name + ":" + requester,
+				Limit: rule.PerRequester,
+			})
+			scopes = append(scopes, "requester")
+		}
+	}
+
+	if i, ok := t.limiter.AllowAll(checks); !ok {
+		return scopes[i], false
+	}
+	return "", true
+}
+
+// Governor returns a mailer middleware that caps the overall send rate,
+// blocking until the bucket allows the next send. It belongs in front of
+// the delivery transport to respect provider rate limits.
+func Governor(bucket *ratelimit.Bucket) Middleware {
+	return func(next Mailer) Mailer {
+		return MailerFunc(func(ctx context.Context, msg *Message) error {
+			if err := bucket.Wait(ctx); err != nil {
+				return err
+			}
+			return next.Send(ctx, msg)
+		})
+	}
+}
+
+type requesterKey struct{}
+
+// WithRequester returns a copy of ctx identifying who triggered the send
+func WithRequester(ctx context.Context, requester string) context.Context {
+	return context.WithValue(ctx, requesterKey{}, requester)
+}
+
+// RequesterFromContext returns the requester stored in ctx, if any
+func RequesterFromContext(ctx context.Context) string {
+	requester, _ := ctx.Value(requesterKey{}).(string)
+	return requester
+}
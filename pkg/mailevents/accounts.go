@@ -0,0 +1,26 @@
+package mailevents
+
+import (
+	"context"
+
+	"github.com/hacKRD0/trikona_go/pkg/accounts"
+	"gorm.io/gorm"
+)
+
+// AccountCascade anonymizes the delivery events of a purged account. The
+// events are kept for delivery statistics, without the address, the raw
+// provider payload or the clicked URLs.
+func AccountCascade() accounts.Cascade {
+	return accounts.Cascade{
+		Name: "email_delivery_events",
+		Purge: func(ctx context.Context, tx *gorm.DB, account *accounts.Account) error {
+			return tx.Model(&Event{}).
+				Where("LOWER(email) = LOWER(?)", account.Email).
+				Updates(map[string]interface{}{
+					"email": account.AnonymizedEmail(),
+					"raw":   nil,
+					"url":   "",
+				}).Error
+		},
+	}
+}
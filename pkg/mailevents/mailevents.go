@@ -0,0 +1,116 @@
+package mailevents
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/suppression"
+	"gorm.io/gorm"
+)
+
+// Event types reported by the email provider
+const (
+	EventSent    = "sent"
+	EventOpen    = "open"
+	EventClick   = "click"
+	EventBounce  = "bounce"
+	EventSpam    = "spam"
+	EventBlocked = "blocked"
+	EventUnsub   = "unsub"
+)
+
+// Event is a delivery event reported by the email provider
+type Event struct {
+	ID                string    `gorm:"type:uuid;primaryKey" json:"id"`
+	Provider          string    `gorm:"size:50;not null" json:"provider"`
+	Type              string    `gorm:"size:20;not null;index" json:"type"`
+	Email             string    `gorm:"size:320;not null;index" json:"email"`
+	ProviderMessageID string    `gorm:"size:100;index" json:"provider_message_id,omitempty"`
+	IdempotencyKey    string    `gorm:"size:255;index" json:"idempotency_key,omitempty"`
+	HardBounce        bool      `json:"hard_bounce,omitempty"`
+	Error             string    `gorm:"size:255" json:"error,omitempty"`
+	ErrorRelatedTo    string    `gorm:"size:50" json:"error_related_to,omitempty"`
+	Source            string    `gorm:"size:50" json:"source,omitempty"`
+	URL               string    `gorm:"type:text" json:"url,omitempty"`
+	OccurredAt        time.Time `gorm:"not null" json:"occurred_at"`
+	Raw               []byte    `gorm:"type:jsonb" json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name used by gorm
+func (Event) TableName() string {
+	return "email_delivery_events"
+}
+
+// BeforeCreate assigns a UUID to new events
+func (e *Event) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// SuppressionReason returns the reason the recipient should be suppressed
+// after this event, or "" if the event does not warrant suppression. Soft
+// bounces are transient and do not suppress the address.
+func (e *Event) SuppressionReason() string {
+	switch e.Type {
+	case EventBounce:
+		if e.HardBounce {
+			return suppression.ReasonHardBounce
+		}
+	case EventSpam:
+		return suppression.ReasonSpam
+	case EventBlocked:
+		return suppression.ReasonBlocked
+	}
+	return ""
+}
+
+// Recorder persists delivery events and applies automatic suppressions
+type Recorder struct {
+	db *gorm.DB
+}
+
+// NewRecorder creates a new event recorder
+func NewRecorder(db *gorm.DB) *Recorder {
+	return &Recorder{db: db}
+}
+
+// Record stores the events and, in the same transaction, suppresses
+// recipients that hard-bounced, complained or were blocked. Provider
+// unsubscribes opt the recipient out of every optional category but keep
+// transactional email flowing.
+func (r *Recorder) Record(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(events).Error; err != nil {
+			return err
+		}
+
+		store := suppression.NewStore(tx)
+		prefs := suppression.NewPreferenceStore(tx)
+		for _, e := range events {
+			if e.Type == EventUnsub {
+				if err := prefs.Set(ctx, e.Email, suppression.CategoryAll, false); err != nil {
+					return err
+				}
+				continue
+			}
+
+			reason := e.SuppressionReason()
+			if reason == "" {
+				continue
+			}
+			if err := store.Suppress(ctx, e.Email, reason, e.Provider, e.Error); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
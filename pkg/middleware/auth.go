@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/auth"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+)
+
+// AuthConfig holds the authentication configuration
+type AuthConfig struct {
+	// JWT validates the bearer tokens
+	JWT auth.JWTService
+	// Optional lets requests without a token through as anonymous; a token
+	// that is sent must still be valid
+	Optional bool
+}
+
+// Auth returns a middleware that validates the bearer token of the
+// Authorization header and stores its claims on the request context (see
+// auth.ClaimsFromContext) and under the "claims", "user_id" and "role" keys
+// of the gin context. Middleware that acts for the user, such as Tenant,
+// StoredLocale and MailRequester, and the audit trail read the claims from
+// there, so it must run before them.
+func Auth(config *AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if config.Optional {
+				c.Next()
+				return
+			}
+			err := errors.NewAuthenticationError("authentication required").
+				WithMessageID("auth.token.missing", nil)
+			c.AbortWithStatusJSON(err.Status, err)
+			return
+		}
+
+		claims, ok := bearerClaims(config.JWT, header)
+		if !ok {
+			err := errors.NewAuthenticationError("invalid or expired token").
+				WithMessageID("auth.token.invalid", nil)
+			c.AbortWithStatusJSON(err.Status, err)
+			return
+		}
+
+		c.Set("claims", claims)
+		c.Set("user_id", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Request = c.Request.WithContext(auth.ContextWithClaims(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+// bearerClaims returns the claims of a valid "Bearer <token>" header
+func bearerClaims(jwtService auth.JWTService, header string) (*auth.Claims, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, false
+	}
+
+	parsed, err := jwtService.ValidateToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil, false
+	}
+	claims, err := jwtService.ExtractClaims(parsed)
+	if err != nil || claims.UserID == "" {
+		return nil, false
+	}
+	return claims, true
+}
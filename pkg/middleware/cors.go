@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,42 +38,76 @@ func Cors(config *CorsConfig) gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		if origin == "" {
-			origin = "*"
-		}
+		handleCors(c, config)
+	}
+}
 
-		// Check if the origin is allowed
-		isAllowed := false
-		for _, allowedOrigin := range config.AllowOrigins {
-			if allowedOrigin == "*" || allowedOrigin == origin {
-				isAllowed = true
-				break
-			}
-		}
+// DynamicCors is a CORS middleware whose configuration can be replaced
+// while serving, e.g. from a config.Watcher subscription
+type DynamicCors struct {
+	config atomic.Pointer[CorsConfig]
+}
 
-		if !isAllowed {
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
+// NewDynamicCors creates a CORS middleware starting with the given
+// configuration
+func NewDynamicCors(config *CorsConfig) *DynamicCors {
+	d := &DynamicCors{}
+	d.Update(config)
+	return d
+}
 
-		// Set CORS headers
-		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowMethods, ","))
-		c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowHeaders, ","))
-		c.Writer.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposeHeaders, ","))
-		c.Writer.Header().Set("Access-Control-Max-Age", string(config.MaxAge))
+// Update replaces the configuration used by subsequent requests
+func (d *DynamicCors) Update(config *CorsConfig) {
+	if config == nil {
+		config = DefaultCorsConfig()
+	}
+	d.config.Store(config)
+}
 
-		if config.AllowCredentials {
-			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		}
+// Handler returns the CORS middleware handler
+func (d *DynamicCors) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handleCors(c, d.config.Load())
+	}
+}
 
-		// Handle preflight requests
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// handleCors applies config to the request
+func handleCors(c *gin.Context, config *CorsConfig) {
+	origin := c.Request.Header.Get("Origin")
+	if origin == "" {
+		origin = "*"
+	}
+
+	// Check if the origin is allowed
+	isAllowed := false
+	for _, allowedOrigin := range config.AllowOrigins {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			isAllowed = true
+			break
 		}
+	}
 
-		c.Next()
+	if !isAllowed {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
 	}
+
+	// Set CORS headers
+	c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+	c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowMethods, ","))
+	c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowHeaders, ","))
+	c.Writer.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposeHeaders, ","))
+	c.Writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+
+	if config.AllowCredentials {
+		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	// Handle preflight requests
+	if c.Request.Method == "OPTIONS" {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	c.Next()
 } 
\ No newline at end of file
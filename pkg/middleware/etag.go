@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+)
+
+// SetETag sets the ETag header to the version of the resource in the response
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", database.FormatETag(version))
+}
+
+// IfMatch returns the version a conditional request expects from its
+// If-Match header, for database.UpdateVersioned. ok is false when the header
+// is absent, or "*" on a DELETE, which matches any version. PUT and PATCH
+// requests must name a version, so "*" is refused for them. Weak tags are
+// accepted, since proxies may weaken the tags they pass on.
+func IfMatch(c *gin.Context) (version int64, ok bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	if header == "*" {
+		if writesRepresentation(c.Request.Method) {
+			return 0, false, ifMatchRequired()
+		}
+		return 0, false, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false, errors.NewValidationError("invalid If-Match header").
+			WithMessageID("validation.if_match.invalid", nil)
+	}
+	version, err = strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, false, errors.NewValidationError("invalid If-Match header").
+			WithMessageID("validation.if_match.invalid", nil)
+	}
+	return version, true, nil
+}
+
+// RequireIfMatch returns a middleware that refuses PUT, PATCH and DELETE
+// requests without an If-Match header with 428 Precondition Required, so
+// clients cannot overwrite changes they have not seen. If-Match: * is
+// refused for PUT and PATCH too, since it matches any version.
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if !writesRepresentation(method) && method != http.MethodDelete {
+			c.Next()
+			return
+		}
+
+		header := strings.TrimSpace(c.GetHeader("If-Match"))
+		if header == "" || (header == "*" && writesRepresentation(method)) {
+			err := ifMatchRequired()
+			c.AbortWithStatusJSON(err.Status, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// writesRepresentation reports whether method replaces or modifies the
+// stored resource with the client's copy
+func writesRepresentation(method string) bool {
+	return method == http.MethodPut || method == http.MethodPatch
+}
+
+func ifMatchRequired() *errors.Error {
+	return errors.NewError(errors.ValidationError, "If-Match header with a version is required", http.StatusPreconditionRequired).
+		WithMessageID("validation.if_match.required", nil)
+}
+
+// VersionConflict sets the ETag header to the current version carried by a
+// conflict error of database.UpdateVersioned, so the client can refetch the
+// resource and merge. It reports whether err was such a conflict.
+func VersionConflict(c *gin.Context, err error) bool {
+	appErr, ok := errors.IsError(err)
+	if !ok || appErr.Type != errors.ConflictError {
+		return false
+	}
+	current, ok := appErr.Meta["current_version"].(int64)
+	if !ok {
+		return false
+	}
+	SetETag(c, current)
+	return true
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/auth"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// LocaleConfig holds the locale negotiation configuration
+type LocaleConfig struct {
+	Catalog *i18n.Catalog
+	// QueryParam lets clients override the negotiated locale, e.g. ?lang=hi
+	QueryParam string
+	// Preference returns the stored locale preference of the current user,
+	// if any; it takes precedence over the Accept-Language header
+	Preference func(c *gin.Context) string
+}
+
+// Locale returns a middleware that negotiates the request locale from the
+// query parameter, the user's preference and the Accept-Language header.
+// The locales are stored on the request context (see i18n.LocalesFromContext)
+// and the resolved locale under the "locale" key of the gin context.
+func Locale(config *LocaleConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var locales []string
+		if config.QueryParam != "" {
+			if lang := c.Query(config.QueryParam); lang != "" {
+				locales = append(locales, lang)
+			}
+		}
+		if config.Preference != nil {
+			if pref := config.Preference(c); pref != "" {
+				locales = append(locales, pref)
+			}
+		}
+		locales = append(locales, i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+
+		localizer := config.Catalog.Localizer(locales...)
+		c.Set("locale", localizer.Locale())
+		c.Set("localizer", localizer)
+		c.Request = c.Request.WithContext(i18n.WithLocales(c.Request.Context(), locales...))
+		c.Header("Content-Language", localizer.Locale())
+
+		c.Next()
+	}
+}
+
+// StoredLocale returns a LocaleConfig.Preference that looks up the stored
+// locale of the authenticated user, e.g. with accounts.Service.Locale. It
+// must run after authentication; anonymous requests have no preference.
+func StoredLocale(lookup func(ctx context.Context, userID uint) (string, error)) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		claims := auth.ClaimsFromContext(c.Request.Context())
+		if claims == nil {
+			return ""
+		}
+		id, err := strconv.ParseUint(claims.UserID, 10, 64)
+		if err != nil {
+			return ""
+		}
+		locale, err := lookup(c.Request.Context(), uint(id))
+		if err != nil {
+			logger.Warn("Failed to load locale preference", zap.String("user_id", claims.UserID), zap.Error(err))
+			return ""
+		}
+		return locale
+	}
+}
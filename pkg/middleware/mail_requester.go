@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/auth"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+)
+
+// MailRequester returns a middleware that tags the request context with the
+// requester so that mail throttling can limit sends per requester. It must
+// run after authentication: authenticated users are keyed by user ID, and
+// anonymous callers by client IP. The IP is taken from X-Forwarded-For only
+// for the proxies passed to the engine's SetTrustedProxies, so set it to
+// the load balancers in front of the service; otherwise clients can spoof
+// the header to get a fresh quota with every request.
+func MailRequester() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requester := "ip:" + c.ClientIP()
+		if claims := auth.ClaimsFromContext(c.Request.Context()); claims != nil && claims.UserID != "" {
+			requester = "user:" + claims.UserID
+		}
+		c.Request = c.Request.WithContext(mailer.WithRequester(c.Request.Context(), requester))
+		c.Next()
+	}
+}
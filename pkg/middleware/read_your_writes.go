@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+)
+
+// ReadYourWrites returns a middleware that sends the request's reads to the
+// primary database once it has written, so it never reads stale data from a
+// replica. Queries must use db.WithContext(c.Request.Context()).
+func ReadYourWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithReadYourWrites(c.Request.Context()))
+		c.Next()
+	}
+}
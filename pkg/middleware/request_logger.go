@@ -18,6 +18,7 @@ func RequestLogger() gin.HandlerFunc {
 			requestID = uuid.New().String()
 			c.Set("request_id", requestID)
 		}
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
 
 		// Capture request details
 		start := time.Now()
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/auth"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/tenant"
+)
+
+// TenantConfig holds the tenant resolution configuration
+type TenantConfig struct {
+	// BaseDomain is the domain whose subdomains name organizations, e.g.
+	// trikona.com for acme.trikona.com; empty disables subdomain resolution
+	BaseDomain string
+	// Lookup returns the ID of the organization with the given slug
+	Lookup func(ctx context.Context, slug string) (uint, error)
+	// Membership returns the organization the user with the given ID
+	// belongs to now, or 0 for none, e.g. tenant.Memberships.TenantOf.
+	// When set, it replaces the token's tenant_id claim, which is only
+	// as current as the token.
+	Membership func(ctx context.Context, userID string) (uint, error)
+	// Required refuses requests that resolve no tenant
+	Required bool
+}
+
+// Tenant returns a middleware that resolves the organization a request acts
+// for and stores it on the request context (see tenant.FromContext) and
+// under the "tenant_id" key of the gin context. It must run after
+// authentication. The tenant comes from the caller's membership (or the
+// token's tenant_id claim without a Membership lookup), from the
+// X-Tenant-ID header for admins, or from the request's subdomain. Only
+// members of the subdomain's tenant and admins may use it; everyone else,
+// anonymous callers included, is refused.
+func Tenant(config *TenantConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := resolveTenant(c, config)
+		if err != nil {
+			appErr, ok := errors.IsError(err)
+			if !ok {
+				appErr = errors.NewInternalError("failed to resolve tenant")
+			}
+			c.AbortWithStatusJSON(appErr.Status, appErr)
+			return
+		}
+		if id == 0 {
+			if config.Required {
+				err := errors.NewAuthorizationError("organization required").
+					WithMessageID("auth.tenant.required", nil)
+				c.AbortWithStatusJSON(err.Status, err)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Set("tenant_id", id)
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// resolveTenant returns the tenant of the request, or 0 for none
+func resolveTenant(c *gin.Context, config *TenantConfig) (uint, error) {
+	ctx := c.Request.Context()
+	claims := auth.ClaimsFromContext(ctx)
+
+	var claimed uint
+	if claims != nil && claims.TenantID != "" {
+		id, err := strconv.ParseUint(claims.TenantID, 10, 64)
+		if err != nil || id == 0 {
+			return 0, errors.NewAuthenticationError("invalid tenant claim")
+		}
+		claimed = uint(id)
+	}
+	// A member removed from or moved to an organization keeps the old claim
+	// until the token expires, so the stored membership wins
+	if claims != nil && config.Membership != nil {
+		id, err := config.Membership(ctx, claims.UserID)
+		if err != nil {
+			return 0, err
+		}
+		claimed = id
+	}
+
+	if header := c.GetHeader("X-Tenant-ID"); header != "" && claimed == 0 {
+		if claims == nil || claims.Role != "admin" {
+			return 0, errors.NewAuthorizationError("only admins may choose a tenant").
+				WithMessageID("auth.tenant.forbidden", nil)
+		}
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil || id == 0 {
+			return 0, errors.NewValidationError("invalid X-Tenant-ID header").
+				WithMessageID("validation.tenant.header_invalid", nil)
+		}
+		return uint(id), nil
+	}
+
+	slug := subdomain(c.Request.Host, config.BaseDomain)
+	if slug == "" || config.Lookup == nil {
+		return claimed, nil
+	}
+	id, err := config.Lookup(ctx, slug)
+	if err != nil {
+		return 0, err
+	}
+	// A subdomain only selects the caller's own organization, or any one
+	// for admins; anonymous callers cannot act for an organization
+	if claimed != id && (claims == nil || claims.Role != "admin") {
+		return 0, errors.NewAuthorizationError("not a member of this organization").
+			WithMessageID("auth.tenant.forbidden", nil)
+	}
+	return id, nil
+}
+
+// subdomain returns the label of host directly below baseDomain, or "" when
+// host is the base domain itself or outside it
+func subdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	suffix := "." + strings.ToLower(baseDomain)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	label := strings.TrimSuffix(host, suffix)
+	if label == "www" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,426 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// lockKey is the Postgres advisory lock key held while migrating, so that
+// replicas booting together run migrations one at a time
+const lockKey int64 = 7_246_105_311
+
+// noTransaction marks a migration file that must run outside a transaction,
+// e.g. for CREATE INDEX CONCURRENTLY
+const noTransaction = "-- migrate:no-transaction"
+
+// ErrChecksumMismatch is returned when an applied migration's file has
+// changed since it was applied
+var ErrChecksumMismatch = errors.New("applied migration has been modified")
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change with its up and down SQL
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum returns the SHA-256 of the up SQL, recorded when the migration
+// is applied to detect later edits
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// String returns the file name stem of the migration
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// AppliedMigration is a row of the schema_migrations table
+type AppliedMigration struct {
+	Version    int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name       string    `gorm:"size:255;not null"`
+	Checksum   string    `gorm:"size:64;not null"`
+	AppliedAt  time.Time `gorm:"not null"`
+	DurationMS int64     `gorm:"not null"`
+}
+
+// TableName overrides the table name used by gorm
+func (AppliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified reports that the file changed after it was applied
+	Modified bool
+}
+
+// Options configures a Migrator
+type Options struct {
+	// DryRun reports what would run, writing the SQL to Out, without
+	// changing the database
+	DryRun bool
+	// Out receives the dry-run SQL; nil discards it
+	Out io.Writer
+}
+
+// Migrator applies and rolls back migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	opts       Options
+}
+
+// Load reads the migrations in the root of fsys. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql; the down file is
+// optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %v", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// New creates a migrator for the migrations in fsys
+func New(db *gorm.DB, fsys fs.FS, opts Options) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	return &Migrator{db: db, migrations: migrations, opts: opts}, nil
+}
+
+// Up applies every pending migration in order and returns those applied.
+// Applied migrations unknown to this build, e.g. from a newer release, are
+// left in place.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.migrate(ctx, m.latest(), false)
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && len(done) < steps; i-- {
+			migration, err := m.find(versions[i])
+			if err != nil {
+				return err
+			}
+			if err := m.rollback(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To migrates up or down so that version is the latest applied migration
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	return m.migrate(ctx, version, true)
+}
+
+func (m *Migrator) migrate(ctx context.Context, version int64, rollback bool) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; rollback && i >= 0 && versions[i] > version; i-- {
+			migration, err := m.find(versions[i])
+			if err != nil {
+				return err
+			}
+			if err := m.rollback(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.apply(conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			status.Modified = row.Checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// locked runs fn on a single connection holding the advisory lock
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", lockKey).Error; err != nil {
+				logger.Error("Failed to release migration lock", err)
+			}
+		}()
+
+		if !m.opts.DryRun {
+			if err := ensureTable(conn); err != nil {
+				return err
+			}
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(conn *gorm.DB) error {
+	err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL,
+	duration_ms BIGINT NOT NULL
+)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+// applied returns the applied migrations by version; none before the
+// schema_migrations table exists
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]AppliedMigration, error) {
+	if !conn.Migrator().HasTable(&AppliedMigration{}) {
+		return map[int64]AppliedMigration{}, nil
+	}
+
+	var rows []AppliedMigration
+	if err := conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+
+	applied := make(map[int64]AppliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// verify fails if an applied migration's file was edited, and warns about
+// applied migrations this build does not know
+func (m *Migrator) verify(applied map[int64]AppliedMigration) error {
+	var modified []string
+	for _, migration := range m.migrations {
+		row, ok := applied[migration.Version]
+		if ok && row.Checksum != migration.Checksum() {
+			modified = append(modified, migration.String())
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+
+	for version, row := range applied {
+		if _, err := m.find(version); err != nil {
+			logger.Warn("Database has a migration unknown to this build",
+				zap.Int64("version", version),
+				zap.String("name", row.Name),
+			)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) error {
+	if m.opts.DryRun {
+		fmt.Fprintf(m.opts.Out, "-- up %s\n%s\n", migration, migration.Up)
+		return nil
+	}
+
+	start := time.Now()
+	err := m.run(conn, migration.Up, func(tx *gorm.DB) error {
+		return tx.Create(&AppliedMigration{
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Checksum:   migration.Checksum(),
+			AppliedAt:  time.Now(),
+			DurationMS: time.Since(start).Milliseconds(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %s: %v", migration, err)
+	}
+
+	logger.Info("Applied migration",
+		zap.String("migration", migration.String()),
+		logger.WithDuration(start),
+	)
+	return nil
+}
+
+func (m *Migrator) rollback(conn *gorm.DB, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %s cannot be rolled back: it has no down file", migration)
+	}
+	if m.opts.DryRun {
+		fmt.Fprintf(m.opts.Out, "-- down %s\n%s\n", migration, migration.Down)
+		return nil
+	}
+
+	start := time.Now()
+	err := m.run(conn, migration.Down, func(tx *gorm.DB) error {
+		return tx.Delete(&AppliedMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration %s: %v", migration, err)
+	}
+
+	logger.Info("Rolled back migration",
+		zap.String("migration", migration.String()),
+		logger.WithDuration(start),
+	)
+	return nil
+}
+
+// run executes sql and then record, in one transaction unless the file is
+// marked to run without one
+func (m *Migrator) run(conn *gorm.DB, sql string, record func(tx *gorm.DB) error) error {
+	if strings.HasPrefix(strings.TrimSpace(sql), noTransaction) {
+		if err := conn.Exec(sql).Error; err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+		return record(tx)
+	})
+}
+
+func (m *Migrator) find(version int64) (Migration, error) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, nil
+		}
+	}
+	return Migration{}, fmt.Errorf("no migration file for applied version %d", version)
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func appliedVersions(applied map[int64]AppliedMigration) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/hacKRD0/trikona_go/pkg/accounts"
+	"gorm.io/gorm"
+)
+
+// AccountCascade deletes the messages of a purged account, whose payloads
+// hold its address and name
+func AccountCascade() accounts.Cascade {
+	return accounts.Cascade{
+		Name: "email_outbox",
+		Purge: func(ctx context.Context, tx *gorm.DB, account *accounts.Account) error {
+			return tx.Where("LOWER(recipient) = LOWER(?)", account.Email).Delete(&Message{}).Error
+		},
+	}
+}
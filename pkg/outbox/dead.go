@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Dead returns up to limit dead-lettered messages, most recent first
+func Dead(ctx context.Context, db *gorm.DB, limit int) ([]Message, error) {
+	var messages []Message
+	err := database.Conn(ctx, db).
+		Where("status = ?", StatusDead).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead messages: %v", err)
+	}
+	return messages, nil
+}
+
+// Retry moves a dead-lettered message back to the pending queue with a
+// fresh set of attempts, e.g. once the provider outage that killed it is
+// over. It returns a not found error when no dead message has the ID.
+func Retry(ctx context.Context, db *gorm.DB, id string) error {
+	result := database.Conn(ctx, db).Model(&Message{}).
+		Where("id = ? AND status = ?", id, StatusDead).
+		Updates(map[string]interface{}{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to retry message: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("dead message not found")
+	}
+	return nil
+}
+
+// PurgeDead deletes the dead messages last attempted more than retention
+// ago, with the personal data in their payloads, and returns how many it
+// deleted
+func PurgeDead(ctx context.Context, db *gorm.DB, retention time.Duration) (int64, error) {
+	result := database.Conn(ctx, db).
+		Where("status = ? AND updated_at < ?", StatusDead, time.Now().Add(-retention)).
+		Delete(&Message{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge dead messages: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
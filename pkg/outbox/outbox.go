@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Message statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+	// StatusSuppressed marks messages dropped because every recipient is
+	// suppressed or opted out
+	StatusSuppressed = "suppressed"
+)
+
+// DefaultMaxAttempts is the number of delivery attempts before a message is dead-lettered
+const DefaultMaxAttempts = 8
+
+// Message is an email waiting to be delivered by the outbox worker
+type Message struct {
+	ID             string     `gorm:"type:uuid;primaryKey" json:"id"`
+	IdempotencyKey string     `gorm:"size:255;not null;uniqueIndex" json:"idempotency_key"`
+	Template       string     `gorm:"size:100" json:"template,omitempty"`
+	Recipient      string     `gorm:"size:320;not null;index" json:"recipient"`
+	Payload        []byte     `gorm:"type:jsonb;not null" json:"-"`
+	Status         string     `gorm:"size:20;not null;default:pending;index:idx_email_outbox_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts    int        `gorm:"not null;default:8" json:"max_attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_email_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name used by gorm
+func (Message) TableName() string {
+	return "email_outbox"
+}
+
+// BeforeCreate assigns a UUID to new messages
+func (m *Message) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Decode returns the mailer message stored in the payload
+func (m *Message) Decode() (*mailer.Message, error) {
+	var msg mailer.Message
+	if err := json.Unmarshal(m.Payload, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox payload: %v", err)
+	}
+	return &msg, nil
+}
+
+// Enqueue stores msg in the outbox using db, which may be a transaction, or
+// the ambient transaction of a database.WithTx unit of work, so that the
+// email is only sent if the surrounding change commits. Messages
+// are deduplicated on msg.IdempotencyKey; enqueueing a key that already
+// exists is a no-op. A random key is generated when none is set.
+func Enqueue(ctx context.Context, db *gorm.DB, msg *mailer.Message) error {
+	if msg == nil || len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %v", err)
+	}
+
+	key := msg.IdempotencyKey
+	if key == "" {
+		key = uuid.New().String()
+	}
+
+	row := &Message{
+		IdempotencyKey: key,
+		Template:       msg.Template,
+		Recipient:      msg.To[0].Email,
+		Payload:        payload,
+		Status:         StatusPending,
+		MaxAttempts:    DefaultMaxAttempts,
+		NextAttemptAt:  time.Now(),
+	}
+
+	err = database.Conn(ctx, db).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "idempotency_key"}}, DoNothing: true}).
+		Create(row).Error
+	if err != nil {
+		return fmt.Errorf("failed to enqueue email: %v", err)
+	}
+	return nil
+}
+
+type outboxMailer struct {
+	db *gorm.DB
+}
+
+// NewMailer returns a mailer that enqueues messages in the outbox instead of
+// sending them. Inside database.WithTx, or when db is a transaction, the
+// email is tied to the surrounding change.
+func NewMailer(db *gorm.DB) mailer.Mailer {
+	return &outboxMailer{db: db}
+}
+
+// Send enqueues the message for delivery by the worker
+func (m *outboxMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	return Enqueue(ctx, m.db, msg)
+}
@@ -0,0 +1,328 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// updateTimeout bounds recording the result of a delivery attempt
+const updateTimeout = 10 * time.Second
+
+// deadPurgeInterval is how often the worker deletes expired dead messages
+const deadPurgeInterval = time.Hour
+
+// clearedPayload replaces the payload of sent and suppressed messages, so
+// that the links and personal data they hold are not kept after sending.
+// Dead messages keep theirs until DeadRetention passes, so they can be
+// inspected and retried.
+var clearedPayload = []byte("{}")
+
+// WorkerConfig holds the outbox worker configuration
+type WorkerConfig struct {
+	// Concurrency is the number of goroutines delivering messages
+	Concurrency int
+	// BatchSize is the maximum number of messages claimed per poll
+	BatchSize int
+	// PollInterval is the delay between polls when the outbox is empty
+	PollInterval time.Duration
+	// Lease is how long a claimed message is hidden from other workers;
+	// messages of a crashed worker become due again once it expires
+	Lease time.Duration
+	// BaseBackoff and MaxBackoff bound the exponential retry delay
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// SendTimeout bounds a single delivery attempt
+	SendTimeout time.Duration
+	// DeadRetention is how long dead messages are kept for Retry before
+	// the worker deletes them; 0 keeps them until PurgeDead is called
+	DeadRetention time.Duration
+}
+
+// DefaultWorkerConfig returns a default worker configuration
+func DefaultWorkerConfig() *WorkerConfig {
+	return &WorkerConfig{
+		Concurrency:   4,
+		BatchSize:     20,
+		PollInterval:  2 * time.Second,
+		Lease:         2 * time.Minute,
+		BaseBackoff:   30 * time.Second,
+		MaxBackoff:    6 * time.Hour,
+		SendTimeout:   30 * time.Second,
+		DeadRetention: 7 * 24 * time.Hour,
+	}
+}
+
+// Worker delivers outbox messages through a mailer
+//
+// Delivery is at-least-once: a message whose worker dies between sending
+// and recording the result is retried once its lease expires. Mailjet
+// receives the idempotency key as CustomID so duplicates can be traced.
+type Worker struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+	config *WorkerConfig
+
+	jobs   chan *Message
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker creates a new outbox worker
+func NewWorker(db *gorm.DB, m mailer.Mailer, config *WorkerConfig) *Worker {
+	if config == nil {
+		config = DefaultWorkerConfig()
+	}
+
+	return &Worker{
+		db:     db,
+		mailer: m,
+		config: config,
+	}
+}
+
+// Start launches the poller and the delivery goroutines. They run until
+// Stop is called or ctx is cancelled.
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.jobs = make(chan *Message, w.config.BatchSize)
+
+	for i := 0; i < w.config.Concurrency; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for msg := range w.jobs {
+				w.deliver(msg)
+			}
+		}()
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(w.jobs)
+		w.poll(ctx)
+	}()
+
+	if w.config.DeadRetention > 0 {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.purgeDead(ctx)
+		}()
+	}
+
+	logger.Info("Outbox worker started", zap.Int("concurrency", w.config.Concurrency))
+}
+
+// Stop stops claiming new messages and waits for in-flight deliveries to
+// finish, or for ctx to expire
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("Outbox worker stopped")
+		return nil
+	case <-ctx.Done():
+		logger.Warn("Outbox worker did not stop before the deadline")
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		claimed, err := w.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Failed to claim outbox messages", err)
+		}
+
+		for _, msg := range claimed {
+			select {
+			case w.jobs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Poll again straight away while there is a backlog
+		if len(claimed) == w.config.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(w.config.PollInterval)
+		}
+	}
+}
+
+// claim locks a batch of due messages and pushes their next attempt past
+// the lease so that concurrent workers skip them
+func (w *Worker) claim(ctx context.Context) ([]*Message, error) {
+	var claimed []*Message
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").
+			Limit(w.config.BatchSize).
+			Find(&claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		ids := make([]string, len(claimed))
+		for i, msg := range claimed {
+			ids[i] = msg.ID
+		}
+		return tx.Model(&Message{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(w.config.Lease)).Error
+	})
+	return claimed, err
+}
+
+func (w *Worker) deliver(row *Message) {
+	// Deliveries use their own context so that Stop lets them finish
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.SendTimeout)
+	defer cancel()
+
+	msg, err := row.Decode()
+	if err == nil {
+		err = w.mailer.Send(ctx, msg)
+	}
+
+	attempts := row.Attempts + 1
+	if err == nil {
+		now := time.Now()
+		w.update(row, map[string]interface{}{
+			"status":     StatusSent,
+			"attempts":   attempts,
+			"sent_at":    now,
+			"last_error": "",
+			"payload":    clearedPayload,
+		})
+		logger.Info("Outbox email sent",
+			zap.String("outbox_id", row.ID),
+			zap.String("template", row.Template),
+			zap.Int("attempts", attempts),
+		)
+		return
+	}
+
+	if errors.Is(err, mailer.ErrSuppressed) {
+		w.update(row, map[string]interface{}{
+			"status":     StatusSuppressed,
+			"attempts":   attempts,
+			"last_error": err.Error(),
+			"payload":    clearedPayload,
+		})
+		logger.Info("Outbox email suppressed",
+			zap.String("outbox_id", row.ID),
+			zap.String("template", row.Template),
+		)
+		return
+	}
+
+	if attempts >= row.MaxAttempts {
+		w.update(row, map[string]interface{}{
+			"status":     StatusDead,
+			"attempts":   attempts,
+			"last_error": err.Error(),
+		})
+		logger.Error("Outbox email dead-lettered", err,
+			zap.String("outbox_id", row.ID),
+			zap.String("template", row.Template),
+			zap.Int("attempts", attempts),
+		)
+		return
+	}
+
+	next := time.Now().Add(w.backoff(attempts))
+	w.update(row, map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": next,
+		"last_error":      err.Error(),
+	})
+	logger.Warn("Outbox email delivery failed, will retry",
+		zap.String("outbox_id", row.ID),
+		zap.String("template", row.Template),
+		zap.Int("attempts", attempts),
+		zap.Time("next_attempt_at", next),
+		zap.Error(err),
+	)
+}
+
+// purgeDead deletes dead messages older than DeadRetention every
+// deadPurgeInterval until ctx is cancelled
+func (w *Worker) purgeDead(ctx context.Context) {
+	ticker := time.NewTicker(deadPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := PurgeDead(ctx, w.db, w.config.DeadRetention)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Failed to purge dead outbox messages", err)
+		}
+		if deleted > 0 {
+			logger.Info("Purged dead outbox messages", zap.Int64("count", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update records the result of a delivery attempt. It runs under its own
+// context, since the send may have used up the attempt's timeout; a failed
+// update would leave the message leased and send it again.
+func (w *Worker) update(row *Message, updates map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	if err := w.db.WithContext(ctx).Model(&Message{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		logger.Error("Failed to update outbox message", err, zap.String("outbox_id", row.ID))
+	}
+}
+
+// backoff returns the exponential delay before the given attempt with up
+// to 20% jitter so that failed batches do not retry in lockstep
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := 1; i < attempts && delay < w.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.config.MaxBackoff {
+		delay = w.config.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
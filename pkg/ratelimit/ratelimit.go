@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit allows Count events per sliding Window
+type Limit struct {
+	Count  int
+	Window time.Duration
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Count > 0 && l.Window > 0
+}
+
+// Limiter is an in-memory sliding window limiter keyed by arbitrary strings.
+// State is per process, so each replica enforces its own limits.
+type Limiter struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+	maxWindow time.Duration
+}
+
+// NewLimiter creates a new sliding window limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		hits:      make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records an event for key and reports whether it is within limit.
+// Rejected events are not recorded.
+func (l *Limiter) Allow(key string, limit Limit) bool {
+	_, ok := l.AllowAll([]Check{{Key: key, Limit: limit}})
+	return ok
+}
+
+// Check is one key and limit of an AllowAll call
+type Check struct {
+	Key   string
+	Limit Limit
+}
+
+// AllowAll records an event for every key if all of them are within their
+// limits. Otherwise nothing is recorded and the index of the first check
+// that failed is returned, so a rejected event uses up no one's quota.
+func (l *Limiter) AllowAll(checks []Check) (int, bool) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, check := range checks {
+		if !check.Limit.Enabled() {
+			continue
+		}
+		if check.Limit.Window > l.maxWindow {
+			l.maxWindow = check.Limit.Window
+		}
+		if len(prune(l.hits[check.Key], now.Add(-check.Limit.Window))) >= check.Limit.Count {
+			return i, false
+		}
+	}
+	l.sweep(now)
+
+	for _, check := range checks {
+		if check.Limit.Enabled() {
+			l.hits[check.Key] = append(prune(l.hits[check.Key], now.Add(-check.Limit.Window)), now)
+		}
+	}
+	return -1, true
+}
+
+// sweep drops keys without recent events so memory stays bounded
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	cutoff := now.Add(-l.maxWindow)
+	for key, hits := range l.hits {
+		if len(hits) == 0 || hits[len(hits)-1].Before(cutoff) {
+			delete(l.hits, key)
+		}
+	}
+}
+
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
+
+// Bucket is a token bucket allowing rate events per second with bursts of
+// up to burst events
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a new token bucket that starts full
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// SetRate changes the rate and burst of the bucket
+func (b *Bucket) SetRate(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.rate = rate
+	b.burst = float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Wait blocks until a token is available or ctx is done. A bucket with a
+// non-positive rate never blocks.
+func (b *Bucket) Wait(ctx context.Context) error {
+	for {
+		delay := b.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available and otherwise returns how long
+// to wait for the next one
+func (b *Bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.refill(time.Now())
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
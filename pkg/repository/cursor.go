@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// cursor is the position after the last row of a page. It records the sort
+// it was made for, so it cannot be replayed against a different order.
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// sortKey identifies an order in a cursor
+func sortKey(order []Order) string {
+	keys := make([]string, len(order))
+	for i, o := range order {
+		keys[i] = o.Column
+		if o.Desc {
+			keys[i] = "-" + o.Column
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// encodeCursor returns the cursor pointing after row
+func encodeCursor(ctx context.Context, sch *schema.Schema, order []Order, row reflect.Value) (string, error) {
+	c := cursor{Sort: sortKey(order), Values: make([]interface{}, len(order))}
+	for i, o := range order {
+		field := sch.LookUpField(o.Column)
+		if field == nil {
+			return "", fmt.Errorf("failed to encode cursor: unknown column %s", o.Column)
+		}
+		value, _ := field.ValueOf(ctx, row)
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		c.Values[i] = value
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort values of a cursor made for order
+func decodeCursor(raw string, order []Order) ([]interface{}, error) {
+	invalid := errors.NewValidationError("invalid cursor").WithMessageID("validation.list.cursor_invalid", nil)
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil || c.Sort != sortKey(order) || len(c.Values) != len(order) {
+		return nil, invalid
+	}
+
+	for i, value := range c.Values {
+		// Numbers are passed as text and converted by the database, so
+		// large integers keep their precision
+		if n, ok := value.(json.Number); ok {
+			c.Values[i] = n.String()
+		}
+	}
+	return c.Values, nil
+}
+
+// after returns the keyset condition selecting the rows that follow values
+// in order:
+//
+//	(a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?)
+func after(order []Order, values []interface{}) clause.Expression {
+	branches := make([]clause.Expression, len(order))
+	for i, o := range order {
+		exprs := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			exprs = append(exprs, clause.Eq{Column: column(order[j].Column), Value: values[j]})
+		}
+		if o.Desc {
+			exprs = append(exprs, clause.Lt{Column: column(o.Column), Value: values[i]})
+		} else {
+			exprs = append(exprs, clause.Gt{Column: column(o.Column), Value: values[i]})
+		}
+		branches[i] = clause.And(exprs...)
+	}
+	return clause.Or(branches...)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type cursorModel struct {
+	ID        uint64
+	Email     string
+	CreatedAt time.Time
+}
+
+func parseSchema(t *testing.T) *schema.Schema {
+	t.Helper()
+	sch, err := schema.Parse(&cursorModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	sch := parseSchema(t)
+	order := withTiebreak([]Order{{Column: "created_at", Desc: true}, {Column: "email"}}, sch)
+	row := cursorModel{
+		ID:        18446744073709551615,
+		Email:     "jane@example.com",
+		CreatedAt: time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.FixedZone("IST", 19800)),
+	}
+
+	raw, err := encodeCursor(context.Background(), sch, order, reflect.ValueOf(row))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := decodeCursor(raw, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"2024-01-02T09:34:05.123456Z", "jane@example.com", "18446744073709551615"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("decodeCursor() = %#v, want %#v", values, want)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	sch := parseSchema(t)
+	order := withTiebreak([]Order{{Column: "email"}}, sch)
+	raw, err := encodeCursor(context.Background(), sch, order, reflect.ValueOf(cursorModel{ID: 1, Email: "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		raw   string
+		order []Order
+	}{
+		{"other sort", raw, withTiebreak([]Order{{Column: "email", Desc: true}}, sch)},
+		{"other columns", raw, withTiebreak([]Order{{Column: "created_at"}}, sch)},
+		{"not base64", "not base64!", order},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("{")), order},
+		{"wrong length", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"email,id","v":["a"]}`)), order},
+	}
+	for _, tt := range tests {
+		_, err := decodeCursor(tt.raw, tt.order)
+		if appErr, ok := errors.IsError(err); !ok || appErr.MessageID != "validation.list.cursor_invalid" {
+			t.Errorf("%s: decodeCursor() error = %v, want cursor_invalid", tt.name, err)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	order := []Order{{Column: "a"}, {Column: "b", Desc: true}, {Column: "id"}}
+	values := []interface{}{1, 2, 3}
+
+	// (a > 1) OR (a = 1 AND b < 2) OR (a = 1 AND b = 2 AND id > 3)
+	want := clause.Or(
+		clause.Gt{Column: column("a"), Value: 1},
+		clause.And(
+			clause.Eq{Column: column("a"), Value: 1},
+			clause.Lt{Column: column("b"), Value: 2},
+		),
+		clause.And(
+			clause.Eq{Column: column("a"), Value: 1},
+			clause.Eq{Column: column("b"), Value: 2},
+			clause.Gt{Column: column("id"), Value: 3},
+		),
+	)
+	if got := after(order, values); !reflect.DeepEqual(got, want) {
+		t.Errorf("after() = %#v, want %#v", got, want)
+	}
+}
+
+func TestWithTiebreak(t *testing.T) {
+	sch := parseSchema(t)
+	tests := []struct {
+		order, want []Order
+	}{
+		{nil, []Order{{Column: "id"}}},
+		{[]Order{{Column: "email"}}, []Order{{Column: "email"}, {Column: "id"}}},
+		{[]Order{{Column: "id", Desc: true}}, []Order{{Column: "id", Desc: true}}},
+	}
+	for _, tt := range tests {
+		if got := withTiebreak(tt.order, sch); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("withTiebreak(%v) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestConditionExpression(t *testing.T) {
+	tests := []struct {
+		condition Condition
+		want      clause.Expression
+	}{
+		{Condition{Column: "role", Op: OpEq, Value: "student"}, clause.Eq{Column: column("role"), Value: "student"}},
+		{Condition{Column: "role", Op: OpNe, Value: "student"}, clause.Neq{Column: column("role"), Value: "student"}},
+		{Condition{Column: "n", Op: OpLte, Value: "5"}, clause.Lte{Column: column("n"), Value: "5"}},
+		{
+			Condition{Column: "role", Op: OpIn, Value: []interface{}{"a", "b"}},
+			clause.IN{Column: column("role"), Values: []interface{}{"a", "b"}},
+		},
+		{
+			Condition{Column: "email", Op: OpLike, Value: `50%_off\`},
+			clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{column("email"), `%50\%\_off\\%`}},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.condition.expression(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.expression() = %#v, want %#v", tt.condition, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+)
+
+// Op is a filter operator
+type Op string
+
+// Filter operators, written as field[op]=value in the query string; a bare
+// field=value means eq
+const (
+	OpEq   Op = "eq"
+	OpNe   Op = "ne"
+	OpLt   Op = "lt"
+	OpLte  Op = "lte"
+	OpGt   Op = "gt"
+	OpGte  Op = "gte"
+	OpLike Op = "like"
+	OpIn   Op = "in"
+)
+
+// Reserved query parameters
+const (
+	paramSort         = "sort"
+	paramLimit        = "limit"
+	paramCursor       = "cursor"
+	paramIncludeTotal = "include_total"
+)
+
+// Field is a field clients may filter or sort a list by
+type Field struct {
+	// Column is the database column behind the field
+	Column string
+	// Ops are the operators the field can be filtered with; a field
+	// without operators cannot be filtered
+	Ops []Op
+	// Sortable allows sorting by the field. Sort columns should be NOT
+	// NULL, since rows with NULL sort values are skipped by cursors.
+	Sortable bool
+	// Parse converts a filter value to the type of the column, e.g.
+	// ParseTime for a timestamp, so a malformed value is rejected with a
+	// validation error instead of failing the query. Values are passed
+	// to the database as strings when it is nil.
+	Parse func(raw string) (interface{}, error)
+}
+
+// ParseInt parses an integer filter value
+func ParseInt(raw string) (interface{}, error) {
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+// ParseBool parses a boolean filter value
+func ParseBool(raw string) (interface{}, error) {
+	return strconv.ParseBool(raw)
+}
+
+// ParseTime parses an RFC 3339 timestamp or a 2006-01-02 date filter value
+func ParseTime(raw string) (interface{}, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+// parse converts a filter value with the field's parser
+func (f Field) parse(raw string) (interface{}, error) {
+	if f.Parse == nil {
+		return raw, nil
+	}
+	return f.Parse(raw)
+}
+
+// ListSpec is the whitelist of fields and the paging limits of a list
+// endpoint. Only fields named here can be used in filters and sorts.
+type ListSpec struct {
+	Fields map[string]Field
+	// DefaultSort applies when the query has no sort, e.g. "-created_at"
+	DefaultSort string
+	// DefaultLimit is the page size when the query has no limit
+	DefaultLimit int
+	// MaxLimit caps the page size a client can ask for
+	MaxLimit int
+}
+
+// withDefaults fills in the page limits spec leaves unset
+func (spec ListSpec) withDefaults() ListSpec {
+	if spec.MaxLimit <= 0 {
+		spec.MaxLimit = MaxLimit
+	}
+	if spec.DefaultLimit <= 0 || spec.DefaultLimit > spec.MaxLimit {
+		spec.DefaultLimit = min(DefaultLimit, spec.MaxLimit)
+	}
+	return spec
+}
+
+// Condition is a parsed filter
+type Condition struct {
+	Column string
+	Op     Op
+	// Value is the filter value as returned by Field.Parse, or a
+	// []interface{} of them for OpIn
+	Value interface{}
+}
+
+// Order is a parsed sort key
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// ListQuery is a parsed list request
+type ListQuery struct {
+	Filters []Condition
+	Sort    []Order
+	// Limit is the page size
+	Limit int
+	// Cursor is the opaque cursor of the page to fetch; empty for the first page
+	Cursor string
+	// IncludeTotal asks for the number of matching rows, which costs an
+	// extra count query
+	IncludeTotal bool
+}
+
+// ParseListQuery parses the query string of a list request against spec:
+//
+//	?status=active&created_at[gte]=2024-01-01&role[in]=student,alumni
+//	&sort=-created_at,email&limit=20&cursor=...&include_total=true
+//
+// Unknown fields, operators the field does not allow, values the field's
+// parser rejects and out of range limits are rejected with a validation
+// error.
+func ParseListQuery(values url.Values, spec ListSpec) (ListQuery, error) {
+	spec = spec.withDefaults()
+	query := ListQuery{
+		Limit:  spec.DefaultLimit,
+		Cursor: values.Get(paramCursor),
+	}
+
+	if raw := values.Get(paramLimit); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > spec.MaxLimit {
+			return ListQuery{}, errors.NewValidationError("limit must be between 1 and "+strconv.Itoa(spec.MaxLimit)).
+				WithMessageID("validation.list.limit_invalid", map[string]interface{}{"max": spec.MaxLimit})
+		}
+		query.Limit = limit
+	}
+
+	if raw := values.Get(paramIncludeTotal); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ListQuery{}, errors.NewValidationError("include_total must be true or false").
+				WithMessageID("validation.list.include_total_invalid", nil)
+		}
+		query.IncludeTotal = include
+	}
+
+	sort := values.Get(paramSort)
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	order, err := parseSort(sort, spec)
+	if err != nil {
+		return ListQuery{}, err
+	}
+	query.Sort = order
+
+	for key, vals := range values {
+		switch key {
+		case paramSort, paramLimit, paramCursor, paramIncludeTotal:
+			continue
+		}
+		name, op := splitFilterKey(key)
+		field, ok := spec.Fields[name]
+		if !ok || len(field.Ops) == 0 {
+			return ListQuery{}, errors.NewValidationError("unknown filter "+name).
+				WithMessageID("validation.list.filter_unknown", map[string]interface{}{"field": name})
+		}
+		if !allows(field, op) {
+			return ListQuery{}, errors.NewValidationError("unsupported operator "+string(op)+" for "+name).
+				WithMessageID("validation.list.filter_operator", map[string]interface{}{"field": name, "op": string(op)})
+		}
+
+		for _, val := range vals {
+			raw := []string{val}
+			if op == OpIn {
+				raw = strings.Split(val, ",")
+			}
+			parsed := make([]interface{}, len(raw))
+			for i, r := range raw {
+				if parsed[i], err = field.parse(r); err != nil {
+					return ListQuery{}, errors.NewValidationError("invalid value for filter "+name).
+						WithMessageID("validation.list.filter_invalid", map[string]interface{}{"field": name})
+				}
+			}
+
+			condition := Condition{Column: field.Column, Op: op, Value: parsed[0]}
+			if op == OpIn {
+				condition.Value = parsed
+			}
+			query.Filters = append(query.Filters, condition)
+		}
+	}
+
+	return query, nil
+}
+
+// parseSort parses a comma separated sort, where a leading - sorts that
+// field in descending order
+func parseSort(sort string, spec ListSpec) ([]Order, error) {
+	if sort == "" {
+		return nil, nil
+	}
+
+	var order []Order
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		desc := strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+
+		field, ok := spec.Fields[name]
+		if !ok || !field.Sortable {
+			return nil, errors.NewValidationError("cannot sort by "+name).
+				WithMessageID("validation.list.sort_unknown", map[string]interface{}{"field": name})
+		}
+		order = append(order, Order{Column: field.Column, Desc: desc})
+	}
+	return order, nil
+}
+
+// splitFilterKey splits field[op] into the field and the operator
+func splitFilterKey(key string) (string, Op) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 || !strings.HasSuffix(key, "]") {
+		return key, OpEq
+	}
+	return key[:open], Op(key[open+1 : len(key)-1])
+}
+
+func allows(field Field, op Op) bool {
+	for _, allowed := range field.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
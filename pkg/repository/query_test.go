@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+)
+
+var testSpec = ListSpec{
+	Fields: map[string]Field{
+		"email":      {Column: "email", Ops: []Op{OpEq, OpLike}, Sortable: true},
+		"role":       {Column: "role", Ops: []Op{OpEq, OpIn}},
+		"created_at": {Column: "created_at", Ops: []Op{OpGte, OpLt}, Sortable: true, Parse: ParseTime},
+		"age":        {Column: "age", Ops: []Op{OpEq, OpIn}, Parse: ParseInt},
+		"name":       {Column: "first_name", Sortable: true},
+	},
+	DefaultSort: "-created_at",
+	MaxLimit:    50,
+}
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  ListQuery
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  ListQuery{Limit: DefaultLimit, Sort: []Order{{Column: "created_at", Desc: true}}},
+		},
+		{
+			name:  "sort, limit, cursor and total",
+			query: "sort=name,-email&limit=50&cursor=abc&include_total=true",
+			want: ListQuery{
+				Limit:        50,
+				Cursor:       "abc",
+				IncludeTotal: true,
+				Sort:         []Order{{Column: "first_name"}, {Column: "email", Desc: true}},
+			},
+		},
+		{
+			name:  "filters",
+			query: "email=jane@example.com&role[in]=student,alumni&age[in]=20,21&created_at[gte]=2024-01-01&created_at[lt]=2025-01-01T10:00:00Z",
+			want: ListQuery{
+				Limit: DefaultLimit,
+				Sort:  []Order{{Column: "created_at", Desc: true}},
+				Filters: []Condition{
+					{Column: "age", Op: OpIn, Value: []interface{}{int64(20), int64(21)}},
+					{Column: "created_at", Op: OpGte, Value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+					{Column: "created_at", Op: OpLt, Value: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)},
+					{Column: "email", Op: OpEq, Value: "jane@example.com"},
+					{Column: "role", Op: OpIn, Value: []interface{}{"student", "alumni"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := ParseListQuery(values, testSpec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		sort.Slice(got.Filters, func(i, j int) bool {
+			a, b := got.Filters[i], got.Filters[j]
+			return a.Column+string(a.Op) < b.Column+string(b.Op)
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseListQuery() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseListQueryRejects(t *testing.T) {
+	tests := map[string]string{
+		"password=x":          "validation.list.filter_unknown",
+		"name=jane":           "validation.list.filter_unknown",
+		"email[gt]=a":         "validation.list.filter_operator",
+		"role[like]=stu":      "validation.list.filter_operator",
+		"sort=role":           "validation.list.sort_unknown",
+		"sort=-password":      "validation.list.sort_unknown",
+		"limit=0":             "validation.list.limit_invalid",
+		"limit=51":            "validation.list.limit_invalid",
+		"limit=ten":           "validation.list.limit_invalid",
+		"include_total=maybe": "validation.list.include_total_invalid",
+		"created_at[gte]=now": "validation.list.filter_invalid",
+		"age[in]=20,twenty":   "validation.list.filter_invalid",
+	}
+	for query, messageID := range tests {
+		values, _ := url.ParseQuery(query)
+		_, err := ParseListQuery(values, testSpec)
+		appErr, ok := errors.IsError(err)
+		if !ok || appErr.Type != errors.ValidationError || appErr.MessageID != messageID {
+			t.Errorf("ParseListQuery(%q) error = %v, want %s", query, err, messageID)
+		}
+	}
+}
+
+func TestListSpecWithDefaults(t *testing.T) {
+	tests := []struct {
+		spec                   ListSpec
+		defaultLimit, maxLimit int
+	}{
+		{ListSpec{}, DefaultLimit, MaxLimit},
+		{ListSpec{MaxLimit: 10}, 10, 10},
+		{ListSpec{DefaultLimit: 30, MaxLimit: 10}, 10, 10},
+		{ListSpec{DefaultLimit: 5}, 5, MaxLimit},
+	}
+	for _, tt := range tests {
+		got := tt.spec.withDefaults()
+		if got.DefaultLimit != tt.defaultLimit || got.MaxLimit != tt.maxLimit {
+			t.Errorf("withDefaults(%+v) limits = %d/%d, want %d/%d",
+				tt.spec, got.DefaultLimit, got.MaxLimit, tt.defaultLimit, tt.maxLimit)
+		}
+	}
+}
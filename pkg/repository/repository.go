@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Page limits used when a ListSpec does not set its own
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Page is the standard envelope of a list response
+type Page[T any] struct {
+	Data       []T      `json:"data"`
+	Pagination PageInfo `json:"pagination"`
+}
+
+// PageInfo describes where a page sits in the list
+type PageInfo struct {
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"has_more"`
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of matching rows, set when asked for
+	Total *int64 `json:"total,omitempty"`
+}
+
+// Repository provides CRUD and cursor paginated lists for the model T,
+// joining the ambient transaction of the context like other stores
+type Repository[T any] struct {
+	db   *gorm.DB
+	spec ListSpec
+}
+
+// New creates a repository for T whose lists accept the fields in spec
+func New[T any](db *gorm.DB, spec ListSpec) *Repository[T] {
+	return &Repository[T]{db: db, spec: spec.withDefaults()}
+}
+
+// Create inserts entity
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return database.Conn(ctx, r.db).Create(entity).Error
+}
+
+// Get returns the record with the given primary key, or a not found error
+func (r *Repository[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	var entity T
+	err := database.Conn(ctx, r.db).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(&entity).Error
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NewNotFoundError("record not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// Update saves every field of entity. Models embedding database.Version are
+// refused, since saving would overwrite concurrent changes; update them with
+// database.UpdateVersioned.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	if _, ok := any(entity).(database.Versioned); ok {
+		return fmt.Errorf("%T is versioned; update it with database.UpdateVersioned", entity)
+	}
+	return database.Conn(ctx, r.db).Save(entity).Error
+}
+
+// Delete deletes the record with the given primary key, or returns a not
+// found error when there is none
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	result := database.Conn(ctx, r.db).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("record not found")
+	}
+	return nil
+}
+
+// ParseQuery parses the query string of a list request against the
+// repository's spec
+func (r *Repository[T]) ParseQuery(values url.Values) (ListQuery, error) {
+	return ParseListQuery(values, r.spec)
+}
+
+// List returns the page of records matching query. Scopes narrow the list
+// further, e.g. to the records of the current user.
+//
+// Pages are fetched by keyset: the cursor holds the sort values of the last
+// row, and the next page starts after them. The primary key is appended to
+// the sort as a tiebreak, so pages neither skip nor repeat rows when rows
+// share sort values or are inserted while paging.
+func (r *Repository[T]) List(ctx context.Context, query ListQuery, scopes ...func(*gorm.DB) *gorm.DB) (*Page[T], error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	order := withTiebreak(query.Sort, sch)
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = r.spec.DefaultLimit
+	}
+	limit = min(limit, r.spec.MaxLimit)
+
+	tx := database.Conn(ctx, r.db).Model(new(T)).Scopes(scopes...)
+	for _, condition := range query.Filters {
+		tx = tx.Where(condition.expression())
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	page := &Page[T]{Data: make([]T, 0, limit), Pagination: PageInfo{Limit: limit}}
+	if query.IncludeTotal {
+		var total int64
+		if err := tx.Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("failed to count records: %v", err)
+		}
+		page.Pagination.Total = &total
+	}
+
+	find := tx
+	if query.Cursor != "" {
+		values, err := decodeCursor(query.Cursor, order)
+		if err != nil {
+			return nil, err
+		}
+		find = find.Where(after(order, values))
+	}
+	for _, o := range order {
+		find = find.Order(clause.OrderByColumn{Column: column(o.Column), Desc: o.Desc})
+	}
+
+	var rows []T
+	if err := find.Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list records: %v", err)
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next, err := encodeCursor(ctx, sch, order, reflect.ValueOf(&rows[limit-1]).Elem())
+		if err != nil {
+			return nil, err
+		}
+		page.Pagination.HasMore = true
+		page.Pagination.NextCursor = next
+	}
+	page.Data = append(page.Data, rows...)
+	return page, nil
+}
+
+// schema returns the parsed gorm schema of T
+func (r *Repository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %v", err)
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("failed to parse model: %s has no primary key", stmt.Schema.Name)
+	}
+	return stmt.Schema, nil
+}
+
+// withTiebreak appends the primary key to order unless it is already sorted by
+func withTiebreak(order []Order, sch *schema.Schema) []Order {
+	pk := sch.PrioritizedPrimaryField.DBName
+	for _, o := range order {
+		if o.Column == pk {
+			return order
+		}
+	}
+	return append(append(make([]Order, 0, len(order)+1), order...), Order{Column: pk})
+}
+
+// column qualifies a column with the model's table, so scopes may join
+// other tables
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+// likeEscaper escapes the LIKE wildcards in a filter value
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// expression returns the SQL condition of a filter. like matches a case
+// insensitive substring.
+func (c Condition) expression() clause.Expression {
+	col := column(c.Column)
+	switch c.Op {
+	case OpNe:
+		return clause.Neq{Column: col, Value: c.Value}
+	case OpLt:
+		return clause.Lt{Column: col, Value: c.Value}
+	case OpLte:
+		return clause.Lte{Column: col, Value: c.Value}
+	case OpGt:
+		return clause.Gt{Column: col, Value: c.Value}
+	case OpGte:
+		return clause.Gte{Column: col, Value: c.Value}
+	case OpLike:
+		pattern := "%" + likeEscaper.Replace(fmt.Sprint(c.Value)) + "%"
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{col, pattern}}
+	case OpIn:
+		values, _ := c.Value.([]interface{})
+		return clause.IN{Column: col, Values: values}
+	default:
+		return clause.Eq{Column: col, Value: c.Value}
+	}
+}
@@ -0,0 +1,26 @@
+package suppression
+
+import (
+	"context"
+
+	"github.com/hacKRD0/trikona_go/pkg/accounts"
+	"gorm.io/gorm"
+)
+
+// AccountCascade deletes the notification preferences and opt-outs of a
+// purged account. Bounce, complaint, block and manual suppressions are kept:
+// they describe the address rather than the account and protect the sender's
+// reputation if it is registered again.
+func AccountCascade() accounts.Cascade {
+	return accounts.Cascade{
+		Name: "notification_preferences",
+		Purge: func(ctx context.Context, tx *gorm.DB, account *accounts.Account) error {
+			email := Normalize(account.Email)
+			if err := tx.Where("email = ?", email).Delete(&Preference{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("email = ? AND reason = ?", email, ReasonUnsubscribe).
+				Delete(&Suppression{}).Error
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package suppression
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"go.uber.org/zap"
+)
+
+// Filter returns a mailer middleware that drops suppressed recipients and
+// recipients who opted out of the message category, and adds one-click
+// unsubscribe headers to email of a category in OptionalCategories.
+// unsubscriber may be nil.
+// It returns mailer.ErrSuppressed when no recipient is left.
+func Filter(store Store, prefs PreferenceStore, unsubscriber *Unsubscriber) mailer.Middleware {
+	return func(next mailer.Mailer) mailer.Mailer {
+		return mailer.MailerFunc(func(ctx context.Context, msg *mailer.Message) error {
+			to := make([]mailer.Address, 0, len(msg.To))
+			for _, addr := range msg.To {
+				suppressed, err := store.IsSuppressed(ctx, addr.Email)
+				if err != nil {
+					return fmt.Errorf("failed to check suppression list: %v", err)
+				}
+				if suppressed {
+					logger.Info("Skipping suppressed recipient", zap.String("template", msg.Template))
+					continue
+				}
+
+				allowed, err := prefs.Allowed(ctx, addr.Email, msg.Category)
+				if err != nil {
+					return fmt.Errorf("failed to check notification preferences: %v", err)
+				}
+				if !allowed {
+					logger.Info("Skipping recipient who opted out",
+						zap.String("template", msg.Template),
+						zap.String("category", msg.Category),
+					)
+					continue
+				}
+
+				to = append(to, addr)
+			}
+			if len(to) == 0 {
+				return mailer.ErrSuppressed
+			}
+
+			out := *msg
+			out.To = to
+			// Unsubscribe links are per recipient, so only single-recipient
+			// messages can carry them. A category nobody can opt out of
+			// gets no link, since following it would fail.
+			if out.Optional() && !registered(out.Category) {
+				logger.Warn("Sending email of an unregistered category without an unsubscribe link",
+					zap.String("template", msg.Template),
+					zap.String("category", msg.Category),
+				)
+			} else if unsubscriber != nil && out.Optional() && len(to) == 1 {
+				headers := make(map[string]string, len(msg.Headers)+2)
+				for k, v := range msg.Headers {
+					headers[k] = v
+				}
+				for k, v := range unsubscriber.Headers(to[0].Email, out.Category) {
+					headers[k] = v
+				}
+				out.Headers = headers
+			}
+
+			return next.Send(ctx, &out)
+		})
+	}
+}
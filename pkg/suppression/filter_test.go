@@ -0,0 +1,52 @@
+package suppression
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"go.uber.org/zap"
+)
+
+// noSuppressions is a Store with an empty suppression list
+type noSuppressions struct{ Store }
+
+func (noSuppressions) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	return false, nil
+}
+
+func TestFilterUnsubscribeHeaders(t *testing.T) {
+	logger.Log = zap.NewNop()
+	u := newTestUnsubscriber(t, memoryPrefs{})
+
+	tests := []struct {
+		category string
+		to       []string
+		want     bool
+	}{
+		{mailer.CategoryProduct, []string{"jane@example.com"}, true},
+		{mailer.CategoryTransactional, []string{"jane@example.com"}, false},
+		{"", []string{"jane@example.com"}, false},
+		{"newsletter", []string{"jane@example.com"}, false},
+		{mailer.CategoryProduct, []string{"jane@example.com", "john@example.com"}, false},
+	}
+	for _, tt := range tests {
+		var sent *mailer.Message
+		next := mailer.MailerFunc(func(ctx context.Context, msg *mailer.Message) error {
+			sent = msg
+			return nil
+		})
+		msg := &mailer.Message{Category: tt.category}
+		for _, email := range tt.to {
+			msg.To = append(msg.To, mailer.Address{Email: email})
+		}
+
+		if err := Filter(noSuppressions{}, memoryPrefs{}, u)(next).Send(context.Background(), msg); err != nil {
+			t.Fatalf("%q to %v: Send() error = %v", tt.category, tt.to, err)
+		}
+		if _, got := sent.Headers["List-Unsubscribe"]; got != tt.want {
+			t.Errorf("%q to %v: List-Unsubscribe set = %v, want %v", tt.category, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package suppression
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CategoryAll selects every optional category, e.g. in an unsubscribe link
+const CategoryAll = "all"
+
+// OptionalCategories lists the categories recipients may opt out of, with
+// their default state when no preference has been stored. Marketing email
+// is opt-in.
+var OptionalCategories = map[string]bool{
+	mailer.CategorySecurity:  true,
+	mailer.CategoryProduct:   true,
+	mailer.CategoryMarketing: false,
+}
+
+// registered reports whether recipients can opt out of the category
+func registered(category string) bool {
+	_, ok := OptionalCategories[category]
+	return ok || category == CategoryAll
+}
+
+// Preference is a recipient's choice for one notification category
+type Preference struct {
+	Email     string    `gorm:"primaryKey;size:320" json:"email"`
+	Category  string    `gorm:"primaryKey;size:50" json:"category"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name used by gorm
+func (Preference) TableName() string {
+	return "notification_preferences"
+}
+
+// PreferenceStore defines the interface for per-recipient notification preferences
+type PreferenceStore interface {
+	Allowed(ctx context.Context, email, category string) (bool, error)
+	Set(ctx context.Context, email, category string, enabled bool) error
+	List(ctx context.Context, email string) (map[string]bool, error)
+}
+
+type preferenceStore struct {
+	db *gorm.DB
+}
+
+// NewPreferenceStore creates a new preference store
+func NewPreferenceStore(db *gorm.DB) PreferenceStore {
+	return &preferenceStore{db: db}
+}
+
+// Allowed reports whether the recipient accepts email of the category.
+// Transactional email is always allowed.
+func (s *preferenceStore) Allowed(ctx context.Context, email, category string) (bool, error) {
+	def, optional := OptionalCategories[category]
+	if !optional {
+		return true, nil
+	}
+
+	var pref Preference
+	err := database.Conn(ctx, s.db).
+		Where("email = ? AND category = ?", Normalize(email), category).
+		Limit(1).Find(&pref).Error
+	if err != nil {
+		return false, err
+	}
+	if pref.Email == "" {
+		return def, nil
+	}
+	return pref.Enabled, nil
+}
+
+// Set stores the recipient's preference; CategoryAll updates every optional category
+func (s *preferenceStore) Set(ctx context.Context, email, category string, enabled bool) error {
+	categories := []string{category}
+	if category == CategoryAll {
+		categories = categories[:0]
+		for c := range OptionalCategories {
+			categories = append(categories, c)
+		}
+	} else if !registered(category) {
+		return fmt.Errorf("unknown notification category %q", category)
+	}
+
+	prefs := make([]Preference, 0, len(categories))
+	for _, c := range categories {
+		prefs = append(prefs, Preference{Email: Normalize(email), Category: c, Enabled: enabled})
+	}
+
+	return database.Conn(ctx, s.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}, {Name: "category"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).
+		Create(&prefs).Error
+}
+
+// List returns the effective state of every optional category
+func (s *preferenceStore) List(ctx context.Context, email string) (map[string]bool, error) {
+	var prefs []Preference
+	if err := database.Conn(ctx, s.db).Where("email = ?", Normalize(email)).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(OptionalCategories))
+	for c, def := range OptionalCategories {
+		out[c] = def
+	}
+	for _, p := range prefs {
+		if _, ok := out[p.Category]; ok {
+			out[p.Category] = p.Enabled
+		}
+	}
+	return out, nil
+}
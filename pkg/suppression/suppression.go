@@ -0,0 +1,97 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Suppression reasons
+const (
+	ReasonHardBounce  = "hard_bounce"
+	ReasonSpam        = "spam_complaint"
+	ReasonBlocked     = "blocked"
+	ReasonUnsubscribe = "unsubscribe"
+	ReasonManual      = "manual"
+)
+
+// Suppression is an address we must not send email to
+type Suppression struct {
+	Email     string    `gorm:"primaryKey;size:320" json:"email"`
+	Reason    string    `gorm:"size:50;not null" json:"reason"`
+	Source    string    `gorm:"size:50" json:"source,omitempty"`
+	Details   string    `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name used by gorm
+func (Suppression) TableName() string {
+	return "email_suppressions"
+}
+
+// Store defines the interface for the email suppression list
+type Store interface {
+	Suppress(ctx context.Context, email, reason, source, details string) error
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+	Get(ctx context.Context, email string) (*Suppression, error)
+	Remove(ctx context.Context, email string) error
+}
+
+type store struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new suppression store
+func NewStore(db *gorm.DB) Store {
+	return &store{db: db}
+}
+
+// Suppress adds the address to the suppression list, keeping the original
+// reason if it is already suppressed
+func (s *store) Suppress(ctx context.Context, email, reason, source, details string) error {
+	return database.Conn(ctx, s.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Suppression{
+			Email:   Normalize(email),
+			Reason:  reason,
+			Source:  source,
+			Details: details,
+		}).Error
+}
+
+// IsSuppressed reports whether the address is on the suppression list
+func (s *store) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var count int64
+	err := database.Conn(ctx, s.db).Model(&Suppression{}).
+		Where("email = ?", Normalize(email)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Get returns the suppression entry for the address, or nil if there is none
+func (s *store) Get(ctx context.Context, email string) (*Suppression, error) {
+	var entry Suppression
+	err := database.Conn(ctx, s.db).Where("email = ?", Normalize(email)).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Remove deletes the address from the suppression list
+func (s *store) Remove(ctx context.Context, email string) error {
+	return database.Conn(ctx, s.db).Where("email = ?", Normalize(email)).Delete(&Suppression{}).Error
+}
+
+// Normalize returns the canonical form of an address used as the list key
+func Normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
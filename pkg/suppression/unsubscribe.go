@@ -0,0 +1,142 @@
+package suppression
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidUnsubscribeToken is returned for tampered or malformed tokens
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// Unsubscriber issues and verifies signed unsubscribe links
+type Unsubscriber struct {
+	secret  []byte
+	baseURL *url.URL
+	prefs   PreferenceStore
+}
+
+// minSecretLength is the shortest secret accepted for signing links
+const minSecretLength = 16
+
+// NewUnsubscriber creates a new unsubscriber. baseURL is the public URL of
+// the unsubscribe endpoint, e.g. https://api.example.com/unsubscribe. A
+// short or empty secret is refused, since it would let anyone forge links.
+func NewUnsubscriber(secret, baseURL string, prefs PreferenceStore) (*Unsubscriber, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("unsubscribe secret must be at least %d characters", minSecretLength)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unsubscribe URL: %v", err)
+	}
+	return &Unsubscriber{
+		secret:  []byte(secret),
+		baseURL: base,
+		prefs:   prefs,
+	}, nil
+}
+
+// URL returns the signed unsubscribe link for the recipient and category,
+// keeping any query parameters of the base URL
+func (u *Unsubscriber) URL(email, category string) string {
+	link := *u.baseURL
+	query := link.Query()
+	query.Set("token", u.Token(email, category))
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// Token returns a signed token identifying the recipient and category
+func (u *Unsubscriber) Token(email, category string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(Normalize(email) + "\n" + category))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(u.sign(payload))
+}
+
+// Verify checks the token signature and returns the recipient and category.
+// Tokens for a category that is no longer registered are rejected.
+func (u *Unsubscriber) Verify(token string) (string, string, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, u.sign(payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	email, category, ok := strings.Cut(string(raw), "\n")
+	if !ok || email == "" || !registered(category) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return email, category, nil
+}
+
+func (u *Unsubscriber) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte("unsubscribe:" + payload))
+	return mac.Sum(nil)
+}
+
+// Headers returns the RFC 2369 and RFC 8058 headers enabling one-click
+// unsubscribe in mail clients
+func (u *Unsubscriber) Headers(email, category string) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + u.URL(email, category) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// Handler returns the unsubscribe endpoint. POST requests (including the
+// RFC 8058 one-click POST sent by mail clients) opt the recipient out
+// immediately; GET requests only describe the pending change so that link
+// scanners cannot unsubscribe users.
+func (u *Unsubscriber) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			token = c.PostForm("token")
+		}
+
+		email, category, err := u.Verify(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if c.Request.Method != http.MethodPost {
+			c.JSON(http.StatusOK, gin.H{
+				"email":    email,
+				"category": category,
+			})
+			return
+		}
+
+		if err := u.prefs.Set(c.Request.Context(), email, category, false); err != nil {
+			logger.Error("Failed to store unsubscribe", err, zap.String("category", category))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+			return
+		}
+
+		logger.Info("Recipient unsubscribed", zap.String("category", category))
+		c.JSON(http.StatusOK, gin.H{
+			"email":        email,
+			"category":     category,
+			"unsubscribed": true,
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package suppression
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// memoryPrefs is an in-memory PreferenceStore
+type memoryPrefs map[string]bool
+
+func (p memoryPrefs) Allowed(ctx context.Context, email, category string) (bool, error) {
+	enabled, ok := p[email+"/"+category]
+	return !ok || enabled, nil
+}
+
+func (p memoryPrefs) Set(ctx context.Context, email, category string, enabled bool) error {
+	p[email+"/"+category] = enabled
+	return nil
+}
+
+func (p memoryPrefs) List(ctx context.Context, email string) (map[string]bool, error) {
+	return nil, nil
+}
+
+func newTestUnsubscriber(t *testing.T, prefs PreferenceStore) *Unsubscriber {
+	t.Helper()
+	u, err := NewUnsubscriber(testSecret, "https://api.example.com/unsubscribe", prefs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestNewUnsubscriberRejectsShortSecret(t *testing.T) {
+	for _, secret := range []string{"", "too-short"} {
+		if _, err := NewUnsubscriber(secret, "https://api.example.com/unsubscribe", nil); err == nil {
+			t.Errorf("NewUnsubscriber(%q) succeeded", secret)
+		}
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	u := newTestUnsubscriber(t, memoryPrefs{})
+
+	email, category, err := u.Verify(u.Token(" Jane@Example.com ", "marketing"))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if email != "jane@example.com" || category != "marketing" {
+		t.Fatalf("Verify() = %q, %q, want jane@example.com, marketing", email, category)
+	}
+
+	token := u.Token("jane@example.com", "marketing")
+	payload, sig, _ := strings.Cut(token, ".")
+	other, err := NewUnsubscriber(strings.Repeat("x", 32), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPayload, _, _ := strings.Cut(other.Token("john@example.com", "marketing"), ".")
+
+	tests := map[string]string{
+		"empty":            "",
+		"no signature":     payload,
+		"bad signature":    payload + ".AAAA",
+		"other secret":     other.Token("jane@example.com", "marketing"),
+		"swapped payload":  otherPayload + "." + sig,
+		"invalid encoding": "!!!." + sig,
+		"unregistered":     u.Token("jane@example.com", "newsletter"),
+	}
+	for name, token := range tests {
+		if _, _, err := u.Verify(token); err != ErrInvalidUnsubscribeToken {
+			t.Errorf("%s: Verify() error = %v, want ErrInvalidUnsubscribeToken", name, err)
+		}
+	}
+}
+
+func TestUnsubscribeURL(t *testing.T) {
+	u := newTestUnsubscriber(t, memoryPrefs{})
+
+	link, err := url.Parse(u.URL("jane@example.com", "marketing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Host != "api.example.com" || link.Path != "/unsubscribe" {
+		t.Fatalf("URL() = %s", link)
+	}
+	if _, _, err := u.Verify(link.Query().Get("token")); err != nil {
+		t.Fatalf("token of URL() does not verify: %v", err)
+	}
+
+	headers := u.Headers("jane@example.com", "marketing")
+	if headers["List-Unsubscribe"] != "<"+link.String()+">" {
+		t.Errorf("List-Unsubscribe = %q", headers["List-Unsubscribe"])
+	}
+	if headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("List-Unsubscribe-Post = %q", headers["List-Unsubscribe-Post"])
+	}
+
+	withQuery, err := NewUnsubscriber(testSecret, "https://example.com/email?action=unsubscribe&token=stale", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err = url.Parse(withQuery.URL("jane@example.com", "marketing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Query().Get("action") != "unsubscribe" || len(link.Query()["token"]) != 1 {
+		t.Fatalf("URL() = %s, want the base query kept and one token", link)
+	}
+	if _, _, err := withQuery.Verify(link.Query().Get("token")); err != nil {
+		t.Fatalf("token of URL() does not verify: %v", err)
+	}
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	logger.Log = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	prefs := memoryPrefs{}
+	u := newTestUnsubscriber(t, prefs)
+	router := gin.New()
+	router.Any("/unsubscribe", u.Handler())
+	token := url.QueryEscape(u.Token("jane@example.com", "marketing"))
+
+	tests := []struct {
+		method string
+		query  string
+		status int
+		optOut bool
+	}{
+		{http.MethodGet, "token=" + token, http.StatusOK, false},
+		{http.MethodGet, "token=invalid", http.StatusBadRequest, false},
+		{http.MethodPost, "token=invalid", http.StatusBadRequest, false},
+		{http.MethodPost, "token=" + token, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, "/unsubscribe?"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s ?%s: status = %d, want %d", tt.method, tt.query, w.Code, tt.status)
+		}
+		allowed, _ := prefs.Allowed(context.Background(), "jane@example.com", "marketing")
+		if allowed == tt.optOut {
+			t.Errorf("%s ?%s: allowed = %v after the request", tt.method, tt.query, allowed)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tenant
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+)
+
+// DefaultMembershipTTL is how long Memberships reuses a looked up membership
+const DefaultMembershipTTL = 30 * time.Second
+
+// maxMemberships bounds the number of cached memberships
+const maxMemberships = 10000
+
+// Memberships caches the organization of each user for the tenant
+// middleware, so a token's tenant claim is checked against users.tenant_id
+// without a query on every request. Membership changes take effect within
+// the TTL on every replica.
+type Memberships struct {
+	store *Store
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[uint]membership
+}
+
+type membership struct {
+	tenantID uint
+	expiry   time.Time
+}
+
+// NewMemberships creates a membership cache over store; a ttl of 0 uses
+// DefaultMembershipTTL
+func NewMemberships(store *Store, ttl time.Duration) *Memberships {
+	if ttl <= 0 {
+		ttl = DefaultMembershipTTL
+	}
+	return &Memberships{store: store, ttl: ttl, entries: map[uint]membership{}}
+}
+
+// TenantOf returns the organization the user with the given token user ID
+// belongs to, or 0 for none, for TenantConfig.Membership
+func (m *Memberships) TenantOf(ctx context.Context, userID string) (uint, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return 0, errors.NewAuthenticationError("invalid user claim")
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	entry, ok := m.entries[uint(id)]
+	m.mu.Unlock()
+	if ok && now.Before(entry.expiry) {
+		return entry.tenantID, nil
+	}
+
+	tenantID, err := m.store.TenantOf(ctx, uint(id))
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= maxMemberships {
+		for key, e := range m.entries {
+			if !now.Before(e.expiry) {
+				delete(m.entries, key)
+			}
+		}
+		if len(m.entries) >= maxMemberships {
+			m.entries = map[uint]membership{}
+		}
+	}
+	m.entries[uint(id)] = membership{tenantID: tenantID, expiry: now.Add(m.ttl)}
+	return tenantID, nil
+}
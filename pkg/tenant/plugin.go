@@ -0,0 +1,120 @@
+package tenant
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Plugin is a gorm plugin scoping tenant-owned models, those embedding
+// Model, to the tenant of the statement's context. Queries, updates and
+// deletes get a tenant_id condition, and creates get the tenant ID. Raw SQL
+// and joined tables are not scoped.
+type Plugin struct{}
+
+// NewPlugin creates the tenant scoping plugin. database.InitDB does not
+// install it, since this package depends on database; the service installs
+// it with db.Use right after InitDB.
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// Name implements gorm.Plugin
+func (p *Plugin) Name() string {
+	return "tenant"
+}
+
+// Initialize implements gorm.Plugin by registering the scoping callbacks
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:scope", p.scope); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tenant:scope", p.scope); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:scope", p.scope); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tenant:scope", p.scope); err != nil {
+		return err
+	}
+	return db.Callback().Create().Before("gorm:create").Register("tenant:assign", p.assign)
+}
+
+// tenantField returns the tenant column of a model, or nil when the model
+// is not tenant-owned
+func tenantField(s *schema.Schema) *schema.Field {
+	if s == nil {
+		return nil
+	}
+	for _, field := range s.Fields {
+		if _, ok := field.TagSettings["TENANT"]; ok {
+			return field
+		}
+	}
+	return nil
+}
+
+// scope restricts a statement to the rows of the context's tenant
+func (p *Plugin) scope(db *gorm.DB) {
+	field := tenantField(db.Statement.Schema)
+	if field == nil || db.Error != nil || isAllTenants(db.Statement.Context) {
+		return
+	}
+
+	id, ok := FromContext(db.Statement.Context)
+	if !ok {
+		db.AddError(ErrNoTenant)
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
+
+// assign sets the tenant of created records, refusing records already
+// assigned to another tenant
+func (p *Plugin) assign(db *gorm.DB) {
+	field := tenantField(db.Statement.Schema)
+	if field == nil || db.Error != nil {
+		return
+	}
+
+	id, ok := FromContext(db.Statement.Context)
+	if !ok {
+		if !isAllTenants(db.Statement.Context) {
+			db.AddError(ErrNoTenant)
+		}
+		return
+	}
+
+	ctx := db.Statement.Context
+	value := reflect.Indirect(db.Statement.ReflectValue)
+	records := []reflect.Value{value}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		records = records[:0]
+		for i := 0; i < value.Len(); i++ {
+			records = append(records, reflect.Indirect(value.Index(i)))
+		}
+	}
+
+	for _, record := range records {
+		if record.Kind() != reflect.Struct {
+			continue
+		}
+		current, zero := field.ValueOf(ctx, record)
+		if zero {
+			if err := field.Set(ctx, record, &id); err != nil {
+				db.AddError(err)
+				return
+			}
+			continue
+		}
+		if owner, ok := current.(*uint); !ok || *owner != id {
+			db.AddError(ErrCrossTenant)
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package tenant
+
+import (
+	"context"
+	stderrors "errors"
+	"regexp"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+	"github.com/hacKRD0/trikona_go/pkg/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// codeUniqueViolation is the Postgres error code of a duplicate key
+const codeUniqueViolation = "23505"
+
+// slugPattern matches slugs usable as a DNS label
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ListSpec whitelists the organization fields admin endpoints may filter
+// and sort by
+var ListSpec = repository.ListSpec{
+	Fields: map[string]repository.Field{
+		"id":         {Column: "id", Sortable: true},
+		"slug":       {Column: "slug", Ops: []repository.Op{repository.OpEq, repository.OpLike}, Sortable: true},
+		"name":       {Column: "name", Ops: []repository.Op{repository.OpLike}, Sortable: true},
+		"type":       {Column: "type", Ops: []repository.Op{repository.OpEq}},
+		"created_at": {Column: "created_at", Ops: []repository.Op{repository.OpGte, repository.OpLt}, Sortable: true, Parse: repository.ParseTime},
+	},
+	DefaultSort: "slug",
+}
+
+// member is the tenant column of a user. Users follow the Model
+// convention: tenant_id is NULL for users outside any organization.
+type member struct {
+	ID uint `gorm:"primaryKey"`
+	Model
+}
+
+// TableName overrides the table name used by gorm
+func (member) TableName() string {
+	return "users"
+}
+
+// Store manages organizations and their members, for admin tooling
+type Store struct {
+	db            *gorm.DB
+	organizations *repository.Repository[Organization]
+}
+
+// NewStore creates a new organization store
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db, organizations: repository.New[Organization](db, ListSpec)}
+}
+
+// Create creates an organization, rejecting invalid or taken slugs
+func (s *Store) Create(ctx context.Context, org *Organization) error {
+	if !slugPattern.MatchString(org.Slug) {
+		return errors.NewValidationError("slug must be a lowercase DNS label").
+			WithMessageID("validation.tenant.slug_invalid", nil)
+	}
+	if org.Type != TypeCompany && org.Type != TypeInstitution {
+		return errors.NewValidationError("type must be company or institution").
+			WithMessageID("validation.tenant.type_invalid", nil)
+	}
+	if org.Name == "" {
+		return errors.NewValidationError("name is required").
+			WithMessageID("validation.name.required", map[string]interface{}{"field": i18n.Ref{ID: "field.name", Default: "name"}})
+	}
+
+	err := database.Conn(ctx, s.db).Create(org).Error
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == codeUniqueViolation {
+		return errors.NewConflictError("slug is already taken")
+	}
+	return err
+}
+
+// Get returns the organization with the given ID
+func (s *Store) Get(ctx context.Context, id uint) (*Organization, error) {
+	return s.organizations.Get(ctx, id)
+}
+
+// GetBySlug returns the organization with the given slug
+func (s *Store) GetBySlug(ctx context.Context, slug string) (*Organization, error) {
+	var org Organization
+	err := database.Conn(ctx, s.db).Where("slug = ?", slug).First(&org).Error
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NewNotFoundError("organization not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Lookup returns the ID of the organization with the given slug, for
+// resolving tenants from subdomains
+func (s *Store) Lookup(ctx context.Context, slug string) (uint, error) {
+	org, err := s.GetBySlug(ctx, slug)
+	if err != nil {
+		return 0, err
+	}
+	return org.ID, nil
+}
+
+// List returns a page of organizations
+func (s *Store) List(ctx context.Context, query repository.ListQuery) (*repository.Page[Organization], error) {
+	return s.organizations.List(ctx, query)
+}
+
+// AddMember makes the user a member of the organization, moving them out
+// of any other. Membership is managed across tenants, so member queries are
+// not scoped to the tenant of ctx.
+func (s *Store) AddMember(ctx context.Context, tenantID, userID uint) error {
+	if _, err := s.Get(ctx, tenantID); err != nil {
+		return err
+	}
+	result := database.Conn(AllTenants(ctx), s.db).Model(&member{ID: userID}).Update("tenant_id", tenantID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("user not found")
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the organization
+func (s *Store) RemoveMember(ctx context.Context, tenantID, userID uint) error {
+	result := database.Conn(AllTenants(ctx), s.db).Model(&member{ID: userID}).
+		Where("tenant_id = ?", tenantID).
+		Update("tenant_id", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("user is not a member of the organization")
+	}
+	return nil
+}
+
+// Members returns the IDs of the organization's members
+func (s *Store) Members(ctx context.Context, tenantID uint) ([]uint, error) {
+	var ids []uint
+	err := database.Conn(AllTenants(ctx), s.db).Model(&member{}).
+		Where("tenant_id = ?", tenantID).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// TenantOf returns the organization the user belongs to now, or 0 when the
+// user belongs to none or does not exist
+func (s *Store) TenantOf(ctx context.Context, userID uint) (uint, error) {
+	var m member
+	err := database.Conn(AllTenants(ctx), s.db).Where("id = ?", userID).Limit(1).Find(&m).Error
+	if err != nil {
+		return 0, err
+	}
+	if m.TenantID == nil {
+		return 0, nil
+	}
+	return *m.TenantID, nil
+}
@@ -0,0 +1,75 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Organization types
+const (
+	TypeCompany     = "company"
+	TypeInstitution = "institution"
+)
+
+// ErrNoTenant is returned for queries on tenant-owned models made without a
+// tenant in the context. Scoping fails closed, so a forgotten tenant is an
+// error rather than a query across every tenant.
+var ErrNoTenant = errors.New("no tenant in context")
+
+// ErrCrossTenant is returned when a record of one tenant is created in the
+// context of another
+var ErrCrossTenant = errors.New("record belongs to another tenant")
+
+// Organization is a tenant: a company or institution whose members share
+// its data
+type Organization struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Slug names the organization's subdomain, e.g. acme for acme.trikona.com
+	Slug      string    `gorm:"size:63;not null;uniqueIndex" json:"slug"`
+	Name      string    `gorm:"size:200;not null" json:"name"`
+	Type      string    `gorm:"size:20;not null" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name used by gorm
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Model is embedded in models owned by a tenant. The Plugin scopes their
+// queries to the tenant of the context and fills in TenantID on create.
+// TenantID is nil for rows that belong to no organization, like users
+// outside any; tenant-scoped queries never match them, so they are only
+// reached through AllTenants. The column is a nullable BIGINT referencing
+// organizations, as users.tenant_id is.
+type Model struct {
+	TenantID *uint `gorm:"index;tenant" json:"tenant_id,omitempty"`
+}
+
+type tenantKey struct{}
+
+type allTenantsKey struct{}
+
+// WithTenant returns a context acting for the tenant
+func WithTenant(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant the context acts for
+func FromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uint)
+	return id, ok && id != 0
+}
+
+// AllTenants returns a context whose queries are not scoped to a tenant,
+// for admin tooling and background jobs that work across tenants
+func AllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+func isAllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey{}).(bool)
+	return all
+}
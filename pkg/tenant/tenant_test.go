@@ -0,0 +1,106 @@
+package tenant
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"github.com/hacKRD0/trikona_go/pkg/testdb"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// job is a tenant-owned model
+type job struct {
+	ID uint `gorm:"primaryKey"`
+	Model
+	Title string
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	db := testdb.Open(t, testdb.Options{
+		Driver: testdb.DriverSQLite,
+		Models: []interface{}{&Organization{}, &member{}, &job{}},
+	}).Begin(t)
+	if err := db.Use(NewPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := AllTenants(context.Background())
+	for _, slug := range []string{"acme", "globex"} {
+		if err := db.WithContext(ctx).Create(&Organization{Slug: slug, Name: slug, Type: TypeCompany}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestPluginScopesByTenant(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	acme, globex := WithTenant(ctx, 1), WithTenant(ctx, 2)
+
+	for _, create := range []struct {
+		ctx   context.Context
+		title string
+	}{{acme, "acme"}, {globex, "globex"}, {AllTenants(ctx), "unowned"}} {
+		if err := db.WithContext(create.ctx).Create(&job{Title: create.title}).Error; err != nil {
+			t.Fatalf("create %s: %v", create.title, err)
+		}
+	}
+
+	var jobs []job
+	if err := db.WithContext(acme).Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Title != "acme" || jobs[0].TenantID == nil || *jobs[0].TenantID != 1 {
+		t.Errorf("acme sees %+v, want only its own job", jobs)
+	}
+
+	if err := db.WithContext(ctx).Find(&jobs).Error; !stderrors.Is(err, ErrNoTenant) {
+		t.Errorf("query without tenant: error = %v, want ErrNoTenant", err)
+	}
+
+	other := uint(2)
+	err := db.WithContext(acme).Create(&job{Title: "stolen", Model: Model{TenantID: &other}}).Error
+	if !stderrors.Is(err, ErrCrossTenant) {
+		t.Errorf("create for another tenant: error = %v, want ErrCrossTenant", err)
+	}
+
+	var unowned job
+	if err := db.WithContext(AllTenants(ctx)).Where("title = ?", "unowned").First(&unowned).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unowned.TenantID != nil {
+		t.Errorf("job created across tenants has tenant %d, want none", *unowned.TenantID)
+	}
+}
+
+func TestStoreMembership(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if err := db.WithContext(AllTenants(ctx)).Create(&member{ID: 7}).Error; err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(db)
+
+	// Membership is managed across tenants, whatever the context acts for
+	if err := store.AddMember(WithTenant(ctx, 2), 1, 7); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := NewMemberships(store, 0).TenantOf(ctx, "7"); err != nil || id != 1 {
+		t.Errorf("TenantOf() = %d, %v after AddMember, want 1", id, err)
+	}
+
+	if err := store.RemoveMember(ctx, 1, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []uint{7, 99} {
+		if id, err := store.TenantOf(ctx, userID); err != nil || id != 0 {
+			t.Errorf("TenantOf(%d) = %d, %v, want 0", userID, id, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// cluster is a throwaway Postgres cluster in a temporary directory. It
+// listens on a Unix socket in that directory only, so clusters of
+// concurrent test binaries don't clash over the port.
+type cluster struct {
+	bin string
+	dir string
+}
+
+// findPostgres returns the directory holding the initdb and pg_ctl
+// binaries: TESTDB_POSTGRES_BIN, the PATH, or the newest Debian-style
+// /usr/lib/postgresql/<version>/bin
+func findPostgres() (string, bool) {
+	candidates := []string{os.Getenv("TESTDB_POSTGRES_BIN")}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		candidates = append(candidates, filepath.Dir(path))
+	}
+	versions, _ := filepath.Glob("/usr/lib/postgresql/*/bin")
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	candidates = append(candidates, versions...)
+
+	for _, dir := range candidates {
+		if dir == "" {
+			continue
+		}
+		if isExecutable(filepath.Join(dir, "initdb")) && isExecutable(filepath.Join(dir, "pg_ctl")) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// startCluster initializes and starts a cluster. Durability is turned off,
+// since the data is thrown away.
+func startCluster() (*cluster, error) {
+	bin, ok := findPostgres()
+	if !ok {
+		return nil, fmt.Errorf("postgres binaries not found; set TESTDB_POSTGRES_BIN")
+	}
+	if os.Geteuid() == 0 {
+		return nil, fmt.Errorf("postgres cannot run as root; run the tests as another user")
+	}
+	dir, err := os.MkdirTemp("", "testdb-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster directory: %v", err)
+	}
+	c := &cluster{bin: bin, dir: dir}
+
+	if out, err := c.run("initdb", "-D", c.dataDir(), "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to initialize postgres cluster: %v: %s", err, out)
+	}
+	options := fmt.Sprintf("-k %s -c listen_addresses='' -c fsync=off -c synchronous_commit=off -c full_page_writes=off", dir)
+	if out, err := c.run("pg_ctl", "-D", c.dataDir(), "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start"); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start postgres cluster: %v: %s", err, out)
+	}
+	return c, nil
+}
+
+// dialector connects to the cluster's postgres database
+func (c *cluster) dialector() gorm.Dialector {
+	return postgres.Open(fmt.Sprintf("host=%s user=postgres dbname=postgres sslmode=disable", c.dir))
+}
+
+// stop shuts the cluster down and removes its directory
+func (c *cluster) stop() error {
+	out, err := c.run("pg_ctl", "-D", c.dataDir(), "-m", "immediate", "-w", "stop")
+	if err != nil {
+		err = fmt.Errorf("failed to stop postgres cluster: %v: %s", err, out)
+	}
+	if removeErr := os.RemoveAll(c.dir); err == nil && removeErr != nil {
+		err = fmt.Errorf("failed to remove cluster directory: %v", removeErr)
+	}
+	return err
+}
+
+func (c *cluster) dataDir() string {
+	return filepath.Join(c.dir, "data")
+}
+
+func (c *cluster) run(name string, args ...string) ([]byte, error) {
+	return exec.Command(filepath.Join(c.bin, name), args...).CombinedOutput()
+}
@@ -0,0 +1,221 @@
+// Package testdb provides isolated databases for tests that touch gorm.
+//
+// A database is a throwaway Postgres cluster, started from the initdb and
+// pg_ctl binaries, with the embedded migrations applied. When Postgres is not
+// installed or cannot start, it falls back to an in-memory SQLite database
+// with the given models auto-migrated, and says so on stderr; DriverPostgres
+// turns the fallback off. SQLite can also be chosen explicitly for code that
+// uses no Postgres-only features; it needs cgo. Each test runs in a
+// transaction that is rolled back when it ends, after loading its YAML
+// fixtures:
+//
+//	var db *testdb.DB
+//
+//	func TestMain(m *testing.M) {
+//		var err error
+//		db, err = testdb.New(testdb.Options{Models: []interface{}{&accounts.Account{}}})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		code := m.Run()
+//		db.Close()
+//		os.Exit(code)
+//	}
+//
+//	func TestDeactivate(t *testing.T) {
+//		tx := db.Begin(t, "testdata/users.yaml")
+//		service := accounts.NewService(tx, &config.AccountConfig{})
+//		...
+//	}
+package testdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hacKRD0/trikona_go/pkg/database"
+	"github.com/hacKRD0/trikona_go/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Drivers
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite diverges from production: the migrations are not applied,
+	// and ILIKE, jsonb, advisory locks, SKIP LOCKED and
+	// database.IsRetryable do not behave as on Postgres. The audit trail
+	// refuses to run on it.
+	DriverSQLite = "sqlite"
+)
+
+// Options configures a test database
+type Options struct {
+	// Driver selects the database; TESTDB_DRIVER overrides it. Empty
+	// means Postgres, falling back to SQLite when Postgres cannot be
+	// started. DriverPostgres never falls back.
+	Driver string
+	// Models are auto-migrated on SQLite, which cannot run the Postgres
+	// migrations. They are ignored on Postgres.
+	Models []interface{}
+	// LogQueries logs every query through the gorm logger
+	LogQueries bool
+}
+
+// DB is an isolated test database
+type DB struct {
+	*gorm.DB
+	// Driver is the driver in use, DriverPostgres or DriverSQLite
+	Driver string
+
+	cluster *cluster
+}
+
+var sqliteSeq atomic.Int64
+
+// fallbackWarning is printed when SQLite replaces a Postgres cluster that
+// could not be started
+const fallbackWarning = "testdb: falling back to SQLite, which does not run the migrations " +
+	"and has no ILIKE, jsonb, advisory locks or SKIP LOCKED; set TESTDB_DRIVER=postgres to fail instead"
+
+// New creates an isolated database. It must be closed with Close. Without a
+// driver, a Postgres cluster that cannot be started is replaced by SQLite,
+// with a warning on stderr listing how SQLite diverges.
+func New(opts Options) (*DB, error) {
+	// The packages under test log through the global logger
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+
+	driver := opts.Driver
+	if env := os.Getenv("TESTDB_DRIVER"); env != "" {
+		driver = env
+	}
+
+	config := &gorm.Config{Logger: gormlogger.Discard}
+	if opts.LogQueries {
+		config.Logger = gormlogger.Default.LogMode(gormlogger.Info)
+	}
+
+	switch driver {
+	case "":
+		c, err := startCluster()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testdb: %v\n%s\n", err, fallbackWarning)
+			return newSQLite(config, opts.Models)
+		}
+		return openPostgres(c, config)
+	case DriverPostgres:
+		c, err := startCluster()
+		if err != nil {
+			return nil, err
+		}
+		return openPostgres(c, config)
+	case DriverSQLite:
+		return newSQLite(config, opts.Models)
+	default:
+		return nil, fmt.Errorf("unknown test database driver %q", driver)
+	}
+}
+
+// Open creates an isolated database for a single test and closes it when
+// the test ends. Unlike a database shared through TestMain, it can be used
+// by parallel tests.
+func Open(t testing.TB, opts Options) *DB {
+	t.Helper()
+	db, err := New(opts)
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close test database: %v", err)
+		}
+	})
+	return db
+}
+
+// openPostgres opens the started cluster c and applies the embedded
+// migrations
+func openPostgres(c *cluster, config *gorm.Config) (*DB, error) {
+	db, err := gorm.Open(c.dialector(), config)
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("failed to open test database: %v", err)
+	}
+	if err := database.Migrate(context.Background(), db); err != nil {
+		closeDB(db)
+		c.stop()
+		return nil, err
+	}
+	return &DB{DB: db, Driver: DriverPostgres, cluster: c}, nil
+}
+
+// newSQLite opens a private in-memory database with models auto-migrated
+func newSQLite(config *gorm.Config, models []interface{}) (*DB, error) {
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared&_fk=1", sqliteSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open test database: %v", err)
+	}
+	// An in-memory database lives as long as its connections; a single
+	// one also keeps SQLite from locking out the test's own transaction
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+
+	if err := db.AutoMigrate(models...); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to migrate test database: %v", err)
+	}
+	return &DB{DB: db, Driver: DriverSQLite}, nil
+}
+
+// Begin starts a transaction for t, loads the fixture files into it and
+// rolls it back when t ends. Code under test should be given the returned
+// transaction as its database; units of work started on it with
+// database.WithTx run in savepoints. Tests sharing a DB must not run in
+// parallel, since SQLite serves one transaction at a time.
+func (d *DB) Begin(t testing.TB, fixtures ...string) *gorm.DB {
+	t.Helper()
+	tx := d.DB.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin test transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+
+	if err := LoadFixtures(tx, fixtures...); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// Close closes the database and stops its Postgres cluster, if any
+func (d *DB) Close() error {
+	err := closeDB(d.DB)
+	if d.cluster != nil {
+		if stopErr := d.cluster.stop(); err == nil {
+			err = stopErr
+		}
+	}
+	return err
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
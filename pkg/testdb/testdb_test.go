@@ -0,0 +1,162 @@
+package testdb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type organization struct {
+	ID        uint `gorm:"primaryKey"`
+	Slug      string
+	Name      string
+	Type      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (organization) TableName() string {
+	return "organizations"
+}
+
+type user struct {
+	ID        uint `gorm:"primaryKey"`
+	Email     string
+	Role      string
+	TenantID  *uint
+	CreatedAt time.Time
+}
+
+func (user) TableName() string {
+	return "users"
+}
+
+func TestSQLite(t *testing.T) {
+	t.Setenv("TESTDB_DRIVER", "")
+	db := Open(t, Options{Driver: DriverSQLite, Models: []interface{}{&organization{}, &user{}}})
+	if db.Driver != DriverSQLite {
+		t.Fatalf("Driver = %q, want %q", db.Driver, DriverSQLite)
+	}
+	testBegin(t, db)
+}
+
+func TestPostgres(t *testing.T) {
+	if _, ok := findPostgres(); !ok {
+		t.Skip("postgres binaries not found")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("postgres cannot run as root")
+	}
+	t.Setenv("TESTDB_DRIVER", "")
+	db := Open(t, Options{})
+	if db.Driver != DriverPostgres {
+		t.Fatalf("Driver = %q, want %q", db.Driver, DriverPostgres)
+	}
+	testBegin(t, db)
+}
+
+func TestFallback(t *testing.T) {
+	if _, ok := findPostgres(); ok && os.Geteuid() != 0 {
+		t.Skip("postgres is available")
+	}
+
+	t.Setenv("TESTDB_DRIVER", "")
+	db := Open(t, Options{Models: []interface{}{&organization{}, &user{}}})
+	if db.Driver != DriverSQLite {
+		t.Fatalf("Driver = %q, want the %q fallback", db.Driver, DriverSQLite)
+	}
+
+	t.Setenv("TESTDB_DRIVER", DriverPostgres)
+	if db, err := New(Options{}); err == nil {
+		db.Close()
+		t.Fatal("New() fell back to SQLite with TESTDB_DRIVER=postgres")
+	}
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	t.Setenv("TESTDB_DRIVER", "mysql")
+	if db, err := New(Options{Driver: DriverSQLite}); err == nil {
+		db.Close()
+		t.Fatal("New() succeeded with TESTDB_DRIVER=mysql")
+	}
+}
+
+// testBegin checks that fixtures are loaded into the test transaction, that
+// new rows get IDs past the fixtures, and that everything is rolled back
+func testBegin(t *testing.T, db *DB) {
+	t.Run("fixtures", func(t *testing.T) {
+		tx := db.Begin(t, "testdata/fixtures.yaml")
+
+		var orgs []organization
+		if err := tx.Order("id").Find(&orgs).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(orgs) != 2 || orgs[0].Slug != "acme" || orgs[1].Type != "institution" {
+			t.Fatalf("organizations = %+v", orgs)
+		}
+
+		var jane user
+		if err := tx.First(&jane, 1).Error; err != nil {
+			t.Fatal(err)
+		}
+		if jane.TenantID == nil || *jane.TenantID != 2 {
+			t.Fatalf("tenant_id = %v, want 2", jane.TenantID)
+		}
+		if want := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC); !jane.CreatedAt.Equal(want) {
+			t.Fatalf("created_at = %v, want %v", jane.CreatedAt, want)
+		}
+
+		org := organization{Slug: "globex", Name: "Globex", Type: "company"}
+		if err := tx.Create(&org).Error; err != nil {
+			t.Fatal(err)
+		}
+		if org.ID != 3 {
+			t.Fatalf("new organization ID = %d, want 3", org.ID)
+		}
+	})
+
+	t.Run("rolled back", func(t *testing.T) {
+		tx := db.Begin(t)
+		var count int64
+		if err := tx.Model(&organization{}).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("%d organizations left after rollback", count)
+		}
+	})
+
+	t.Run("missing fixture file", func(t *testing.T) {
+		tx := db.Begin(t)
+		if err := LoadFixtures(tx, "testdata/missing.yaml"); err == nil {
+			t.Fatal("LoadFixtures() succeeded with a missing file")
+		}
+	})
+}
+
+func TestParseFixtures(t *testing.T) {
+	fixtures, err := ParseFixtures([]byte(`
+users:
+  - id: 1
+    settings: {theme: dark}
+organizations:
+  - id: 1
+    tags: [a, b]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 2 || fixtures[0].Table != "users" || fixtures[1].Table != "organizations" {
+		t.Fatalf("fixtures = %+v, want users then organizations", fixtures)
+	}
+	if got := fixtures[0].Rows[0]["settings"]; got != `{"theme":"dark"}` {
+		t.Errorf("settings = %v, want JSON", got)
+	}
+	if got := fixtures[1].Rows[0]["tags"]; got != `["a","b"]` {
+		t.Errorf("tags = %v, want JSON", got)
+	}
+
+	if _, err := ParseFixtures([]byte("- id: 1")); err == nil {
+		t.Error("ParseFixtures() accepted a list")
+	}
+}
@@ -1,162 +1,125 @@
 package utils
 
 import (
-	"errors"
-	"fmt"
-	"os"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
 
-	"github.com/mailjet/mailjet-apiv3-go"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+	"github.com/hacKRD0/trikona_go/pkg/mailer"
+	"github.com/hacKRD0/trikona_go/pkg/suppression"
 )
 
-// EmailConfig holds the Mailjet configuration
-type EmailConfig struct {
-	APIKey    string
-	SecretKey string
-	FromEmail string
-	FromName  string
+// EmailService defines the interface for sending application emails
+//
+// Emails are rendered in the locales carried by ctx (see i18n.WithLocales);
+// callers should put the recipient's stored locale preference first.
+type EmailService interface {
+	SendEmailVerification(ctx context.Context, email, token string) error
+	SendPasswordResetEmail(ctx context.Context, email, firstName, token string) error
+	SendWelcomeEmail(ctx context.Context, email, firstName string) error
+	SendInviteEmail(ctx context.Context, email, inviterName, organizationName, token string) error
+	SendSecurityAlertEmail(ctx context.Context, email, firstName string, alert SecurityAlert) error
 }
 
-// NewEmailConfig creates a new email configuration from environment variables
-func NewEmailConfig() *EmailConfig {
-	return &EmailConfig{
-		APIKey:    os.Getenv("MAILJET_API_KEY"),
-		SecretKey: os.Getenv("MAILJET_SECRET_KEY"),
-		FromEmail: os.Getenv("MAILJET_FROM_EMAIL"),
-		FromName:  os.Getenv("MAILJET_FROM_NAME"),
+// SecurityAlert describes account activity reported in a security alert email
+type SecurityAlert struct {
+	Event     string
+	Time      time.Time
+	IPAddress string
+}
+
+type emailService struct {
+	mailer       mailer.Mailer
+	templates    *mailer.Registry
+	unsubscriber *suppression.Unsubscriber
+	frontendURL  string
+}
+
+// NewEmailService creates a new email service delivering through the given
+// mailer. When unsubscriber is set, optional emails carry an unsubscribe link.
+func NewEmailService(m mailer.Mailer, templates *mailer.Registry, unsubscriber *suppression.Unsubscriber, frontendURL string) EmailService {
+	return &emailService{
+		mailer:       m,
+		templates:    templates,
+		unsubscriber: unsubscriber,
+		frontendURL:  frontendURL,
 	}
 }
 
 // SendEmailVerification sends an email verification link to the user
-func SendEmailVerification(email, token string) error {
-	config := NewEmailConfig()
-	
-	// Initialize Mailjet client
-	mailjetClient := mailjet.NewMailjetClient(config.APIKey, config.SecretKey)
-	if mailjetClient == nil {
-		return errors.New("failed to create Mailjet client")
+func (s *emailService) SendEmailVerification(ctx context.Context, email, token string) error {
+	return s.send(ctx, email, mailer.TemplateVerifyEmail, mailer.CategoryTransactional, idempotencyKey(mailer.TemplateVerifyEmail, token), map[string]interface{}{
+		"Link": s.link("/register", token),
+	})
+}
+
+// SendPasswordResetEmail sends a password reset link to the user
+func (s *emailService) SendPasswordResetEmail(ctx context.Context, email, firstName, token string) error {
+	return s.send(ctx, email, mailer.TemplatePasswordReset, mailer.CategoryTransactional, idempotencyKey(mailer.TemplatePasswordReset, token), map[string]interface{}{
+		"FirstName": firstName,
+		"Link":      s.link("/reset-password", token),
+	})
+}
+
+// SendWelcomeEmail sends a welcome email once the user has verified their address
+func (s *emailService) SendWelcomeEmail(ctx context.Context, email, firstName string) error {
+	return s.send(ctx, email, mailer.TemplateWelcome, mailer.CategoryProduct, idempotencyKey(mailer.TemplateWelcome, email), map[string]interface{}{
+		"FirstName": firstName,
+		"Link":      s.link("/profile", ""),
+	})
+}
+
+// SendInviteEmail sends an invitation to join an organization
+func (s *emailService) SendInviteEmail(ctx context.Context, email, inviterName, organizationName, token string) error {
+	return s.send(ctx, email, mailer.TemplateInvite, mailer.CategoryTransactional, idempotencyKey(mailer.TemplateInvite, token), map[string]interface{}{
+		"InviterName":      inviterName,
+		"OrganizationName": organizationName,
+		"Link":             s.link("/invite", token),
+	})
+}
+
+// SendSecurityAlertEmail notifies the user about sensitive account activity
+func (s *emailService) SendSecurityAlertEmail(ctx context.Context, email, firstName string, alert SecurityAlert) error {
+	return s.send(ctx, email, mailer.TemplateSecurityAlert, mailer.CategorySecurity, "", map[string]interface{}{
+		"FirstName": firstName,
+		"Event":     alert.Event,
+		"Time":      alert.Time.UTC().Format(time.RFC1123),
+		"IPAddress": alert.IPAddress,
+		"Link":      s.link("/reset-password", ""),
+	})
+}
+
+func (s *emailService) send(ctx context.Context, email, template, category, key string, data map[string]interface{}) error {
+	if s.unsubscriber != nil && category != mailer.CategoryTransactional {
+		data["UnsubscribeURL"] = s.unsubscriber.URL(email, category)
 	}
 
-	// Email content
-	subject := "Verify your email address"
-	textBody := fmt.Sprintf(`
-		Hello,
-		
-		Please click the following link to verify your email address:
-		%s/register?token=%s
-		
-		This link will expire in 24 hours.
-		
-		If you did not request this verification, please ignore this email.
-	`, os.Getenv("FRONTEND_URL"), token)
-
-	htmlBody := fmt.Sprintf(`
-		<table width="100%%" cellpadding="0" cellspacing="0" border="0">
-			<tr>
-				<td style="padding: 20px; font-family: Arial, sans-serif; line-height: 1.6;">
-					<h2 style="color: #333333; margin-bottom: 20px;">Email Verification</h2>
-					<p style="margin-bottom: 20px;">Hello,</p>
-					<p style="margin-bottom: 20px;">Thank you for registering with us. Please verify your email address by clicking the button below:</p>
-					<table cellpadding="0" cellspacing="0" border="0" style="margin: 20px 0;">
-						<tr>
-							<td align="center" bgcolor="#4CAF50" style="border-radius: 5px;">
-								<a href="%s/register?token=%s" target="_blank" style="padding: 10px 20px; font-size: 16px; color: #ffffff; text-decoration: none; display: inline-block;">Verify Email Address</a>
-							</td>
-						</tr>
-					</table>
-					<p style="margin-bottom: 20px;">Or copy and paste this link into your browser:</p>
-					<p style="margin-bottom: 20px; word-break: break-all;">%s/register?token=%s</p>
-					<p style="margin-bottom: 20px; color: #666666; font-size: 14px;">This link will expire in 24 hours.</p>
-					<p style="margin-bottom: 20px; color: #666666; font-size: 14px;">If you did not request this verification, please ignore this email.</p>
-				</td>
-			</tr>
-		</table>
-	`, os.Getenv("FRONTEND_URL"), token, os.Getenv("FRONTEND_URL"), token)
-
-	// Create email message
-	messagesInfo := []mailjet.InfoMessagesV31{
-		{
-			From: &mailjet.RecipientV31{
-				Email: config.FromEmail,
-				Name:  config.FromName,
-			},
-			To: &mailjet.RecipientsV31{
-				mailjet.RecipientV31{
-					Email: email,
-				},
-			},
-			Subject:  subject,
-			TextPart: textBody,
-			HTMLPart: htmlBody,
-		},
+	msg, err := s.templates.Render(template, i18n.LocalesFromContext(ctx), data)
+	if err != nil {
+		return err
 	}
+	msg.To = []mailer.Address{{Email: email}}
+	msg.Category = category
+	msg.IdempotencyKey = key
 
-	// Send the email
-	_, err := mailjetClient.SendMailV31(&mailjet.MessagesV31{Info: messagesInfo})
-	return err
+	return s.mailer.Send(ctx, msg)
 }
 
-// SendPasswordResetEmail sends a password reset link to the user
-func SendPasswordResetEmail(email, firstName, token string) error {
-	config := NewEmailConfig()
-	
-	// Initialize Mailjet client
-	mailjetClient := mailjet.NewMailjetClient(config.APIKey, config.SecretKey)
-
-	// Email content
-	subject := "Reset your password"
-	textBody := fmt.Sprintf(`
-		Hello %s,
-		
-		You have requested to reset your password. Please click the following link to reset it:
-		%s/reset-password?token=%s
-		
-		This link will expire in 3 hours.
-		
-		If you did not request this password reset, please ignore this email.
-	`, firstName, os.Getenv("FRONTEND_URL"), token)
-
-	htmlBody := fmt.Sprintf(`
-		<table width="100%%" cellpadding="0" cellspacing="0" border="0">
-			<tr>
-				<td style="padding: 20px; font-family: Arial, sans-serif; line-height: 1.6;">
-					<h2 style="color: #333333; margin-bottom: 20px;">Password Reset Request</h2>
-					<p style="margin-bottom: 20px;">Hello %s,</p>
-					<p style="margin-bottom: 20px;">We received a request to reset your password. Click the button below to create a new password:</p>
-					<table cellpadding="0" cellspacing="0" border="0" style="margin: 20px 0;">
-						<tr>
-							<td align="center" bgcolor="#2196F3" style="border-radius: 5px;">
-								<a href="%s/reset-password?token=%s" target="_blank" style="padding: 10px 20px; font-size: 16px; color: #ffffff; text-decoration: none; display: inline-block;">Reset Password</a>
-							</td>
-						</tr>
-					</table>
-					<p style="margin-bottom: 20px;">Or copy and paste this link into your browser:</p>
-					<p style="margin-bottom: 20px; word-break: break-all;">%s/reset-password?token=%s</p>
-					<p style="margin-bottom: 20px; color: #666666; font-size: 14px;">This link will expire in 3 hours.</p>
-					<p style="margin-bottom: 20px; color: #666666; font-size: 14px;">If you did not request this password reset, please ignore this email.</p>
-				</td>
-			</tr>
-		</table>
-	`, firstName, os.Getenv("FRONTEND_URL"), token, os.Getenv("FRONTEND_URL"), token)
-
-	// Create email message
-	messagesInfo := []mailjet.InfoMessagesV31{
-		{
-			From: &mailjet.RecipientV31{
-				Email: config.FromEmail,
-				Name:  config.FromName,
-			},
-			To: &mailjet.RecipientsV31{
-				mailjet.RecipientV31{
-					Email: email,
-				},
-			},
-			Subject:  subject,
-			TextPart: textBody,
-			HTMLPart: htmlBody,
-		},
+// link builds a frontend URL, attaching the token as an escaped query parameter
+func (s *emailService) link(path, token string) string {
+	link := s.frontendURL + path
+	if token != "" {
+		link += "?" + url.Values{"token": {token}}.Encode()
 	}
+	return link
+}
 
-	// Send the email
-	_, err := mailjetClient.SendMailV31(&mailjet.MessagesV31{Info: messagesInfo})
-	return err
-} 
\ No newline at end of file
+// idempotencyKey derives a stable key for a logical send without storing
+// the raw token alongside the message
+func idempotencyKey(template, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return template + ":" + hex.EncodeToString(sum[:])
+}
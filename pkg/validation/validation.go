@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
 )
 
 // ValidationError represents a validation error
@@ -18,11 +19,11 @@ type ValidationError struct {
 // ValidateEmail validates an email address
 func ValidateEmail(email string) error {
 	if email == "" {
-		return errors.NewValidationError("email is required")
+		return errors.NewValidationError("email is required").WithMessageID("validation.email.required", nil)
 	}
 
 	if _, err := mail.ParseAddress(email); err != nil {
-		return errors.NewValidationError("invalid email format")
+		return errors.NewValidationError("invalid email format").WithMessageID("validation.email.invalid", nil)
 	}
 
 	return nil
@@ -31,53 +32,70 @@ func ValidateEmail(email string) error {
 // ValidatePassword validates a password
 func ValidatePassword(password string) error {
 	if password == "" {
-		return errors.NewValidationError("password is required")
+		return errors.NewValidationError("password is required").WithMessageID("validation.password.required", nil)
 	}
 
 	if len(password) < 8 {
-		return errors.NewValidationError("password must be at least 8 characters long")
+		return errors.NewValidationError("password must be at least 8 characters long").
+			WithMessageID("validation.password.too_short", map[string]interface{}{"count": 8})
 	}
 
 	// Check for at least one uppercase letter
 	if !regexp.MustCompile(`[A-Z]`).MatchString(password) {
-		return errors.NewValidationError("password must contain at least one uppercase letter")
+		return errors.NewValidationError("password must contain at least one uppercase letter").
+			WithMessageID("validation.password.uppercase", nil)
 	}
 
 	// Check for at least one lowercase letter
 	if !regexp.MustCompile(`[a-z]`).MatchString(password) {
-		return errors.NewValidationError("password must contain at least one lowercase letter")
+		return errors.NewValidationError("password must contain at least one lowercase letter").
+			WithMessageID("validation.password.lowercase", nil)
 	}
 
 	// Check for at least one number
 	if !regexp.MustCompile(`[0-9]`).MatchString(password) {
-		return errors.NewValidationError("password must contain at least one number")
+		return errors.NewValidationError("password must contain at least one number").
+			WithMessageID("validation.password.number", nil)
 	}
 
 	// Check for at least one special character
 	if !regexp.MustCompile(`[!@#$%^&*]`).MatchString(password) {
-		return errors.NewValidationError("password must contain at least one special character (!@#$%^&*)")
+		return errors.NewValidationError("password must contain at least one special character (!@#$%^&*)").
+			WithMessageID("validation.password.special", map[string]interface{}{"characters": "!@#$%^&*"})
 	}
 
 	return nil
 }
 
-// ValidateName validates a name (first or last)
+// ValidateName validates a name (first or last). fieldName is the English
+// field name, e.g. "first name", which also selects the translated name
+// ("field.first_name") used in localized messages.
 func ValidateName(name string, fieldName string) error {
+	field := i18n.Ref{ID: fieldMessageID(fieldName), Default: fieldName}
 	if name == "" {
-		return errors.NewValidationError(fmt.Sprintf("%s is required", fieldName))
+		return errors.NewValidationError(fmt.Sprintf("%s is required", fieldName)).
+			WithMessageID("validation.name.required", map[string]interface{}{"field": field})
 	}
 
 	if len(name) < 2 {
-		return errors.NewValidationError(fmt.Sprintf("%s must be at least 2 characters long", fieldName))
+		return errors.NewValidationError(fmt.Sprintf("%s must be at least 2 characters long", fieldName)).
+			WithMessageID("validation.name.too_short", map[string]interface{}{"field": field, "count": 2})
 	}
 
 	if !regexp.MustCompile(`^[a-zA-Z]+$`).MatchString(name) {
-		return errors.NewValidationError(fmt.Sprintf("%s can only contain letters", fieldName))
+		return errors.NewValidationError(fmt.Sprintf("%s can only contain letters", fieldName)).
+			WithMessageID("validation.name.letters_only", map[string]interface{}{"field": field})
 	}
 
 	return nil
 }
 
+// fieldMessageID returns the catalog ID of a field name, e.g. "field.first_name"
+// for "First Name"
+func fieldMessageID(fieldName string) string {
+	return "field." + strings.Join(strings.Fields(strings.ToLower(fieldName)), "_")
+}
+
 // ValidateLinkedInURL validates a LinkedIn URL
 func ValidateLinkedInURL(url string) error {
 	if url == "" {
@@ -85,7 +103,7 @@ func ValidateLinkedInURL(url string) error {
 	}
 
 	if !strings.HasPrefix(url, "https://www.linkedin.com/") {
-		return errors.NewValidationError("invalid LinkedIn URL format")
+		return errors.NewValidationError("invalid LinkedIn URL format").WithMessageID("validation.linkedin_url.invalid", nil)
 	}
 
 	return nil
@@ -97,14 +115,28 @@ func ValidateRole(role string) error {
 		"student":      true,
 		"professional": true,
 		"company":      true,
+		"institution":  true,
 		"moderator":    true,
 		"admin":        true,
 		"guest":        true,
 	}
 
 	if !validRoles[role] {
-		return errors.NewValidationError("invalid role")
+		return errors.NewValidationError("invalid role").WithMessageID("validation.role.invalid", nil)
+	}
+
+	return nil
+} 
+
+// ValidateLocale validates a user's locale preference against the catalog
+func ValidateLocale(locale string, catalog *i18n.Catalog) error {
+	if locale == "" {
+		return nil // Locale preference is optional
+	}
+
+	if !catalog.Supports(locale) {
+		return errors.NewValidationError("unsupported locale").WithMessageID("validation.locale.unsupported", nil)
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
\ No newline at end of file
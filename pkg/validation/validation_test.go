@@ -0,0 +1,39 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/hacKRD0/trikona_go/pkg/errors"
+	"github.com/hacKRD0/trikona_go/pkg/i18n"
+)
+
+func TestValidateNameLocalizesField(t *testing.T) {
+	catalog, err := i18n.NewCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, fieldName, locale, want string
+	}{
+		{"", "first name", "en", "first name is required"},
+		{"", "first name", "hi", "पहला नाम आवश्यक है"},
+		{"J", "Last Name", "hi", "उपनाम कम से कम 2 अक्षरों का होना चाहिए"},
+		{"J0hn", "last_name", "en", "last name can only contain letters"},
+		{"", "nickname", "hi", "nickname आवश्यक है"},
+	}
+	for _, tt := range tests {
+		err := ValidateName(tt.name, tt.fieldName)
+		appErr, ok := errors.IsError(err)
+		if !ok {
+			t.Fatalf("ValidateName(%q, %q) error = %v", tt.name, tt.fieldName, err)
+		}
+		if got := appErr.Localize(catalog.Localizer(tt.locale)).Message; got != tt.want {
+			t.Errorf("ValidateName(%q, %q) in %s = %q, want %q", tt.name, tt.fieldName, tt.locale, got, tt.want)
+		}
+	}
+
+	if err := ValidateName("Jane", "first name"); err != nil {
+		t.Errorf("ValidateName(Jane) error = %v", err)
+	}
+}